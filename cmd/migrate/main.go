@@ -0,0 +1,73 @@
+// Command migrate applies, reverts, and lists the schema migrations of the
+// database named by DATABASE_URL.
+//
+// Usage:
+//
+//	migrate up      apply every pending migration
+//	migrate down    revert the latest applied migration
+//	migrate status  list migrations and whether they have been applied
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/fflewddur/pantry/store"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		usage()
+	}
+	db, err := store.OpenEnv()
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	switch os.Args[1] {
+	case "up":
+		applied, err := store.Up(ctx, db)
+		for _, m := range applied {
+			log.Printf("Applied migration %d: %s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("Failed to migrate: %v", err)
+		}
+		if len(applied) == 0 {
+			log.Println("Schema is already up to date.")
+		}
+	case "down":
+		m, err := store.Down(ctx, db)
+		if err != nil {
+			log.Fatalf("Failed to revert migration: %v", err)
+		}
+		if m == nil {
+			log.Println("No migrations to revert.")
+			return
+		}
+		log.Printf("Reverted migration %d: %s", m.Version, m.Name)
+	case "status":
+		version, err := store.SchemaVersion(ctx, db)
+		if err != nil {
+			log.Fatalf("Failed to read schema version: %v", err)
+		}
+		for _, m := range db.Migrations() {
+			state := "pending"
+			if m.Version <= version {
+				state = "applied"
+			}
+			fmt.Printf("%4d  %-8s %s\n", m.Version, state, m.Name)
+		}
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: migrate up|down|status")
+	os.Exit(2)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/fflewddur/pantry/proxy"
+	"github.com/fflewddur/pantry/store"
+)
+
+// maxIndexLimit is the most entries index.golang.org returns per request.
+const maxIndexLimit = 2000
+
+// The position of the scanner in the index is saved in utils under two keys:
+// the timestamp of the newest queued entry, and how many entries with that
+// exact timestamp have been queued. The index returns entries at or after a
+// timestamp, so the next page starts with entries that were already queued,
+// and the offset skips them.
+const (
+	cursorKey       = "since"
+	cursorOffsetKey = "since_offset"
+)
+
+// errIndexStuck is returned when the index cannot be read past the cursor.
+var errIndexStuck = errors.New("cannot read the index past the cursor")
+
+// indexCursor is a position in the index feed.
+type indexCursor struct {
+	since  time.Time
+	offset int // Entries at exactly since that have been queued
+}
+
+// loadCursor returns the saved position in the index, or the start of the
+// index if there is none.
+func (s *Scanner) loadCursor(ctx context.Context) (indexCursor, error) {
+	var c indexCursor
+	v, err := s.db.Cursor(ctx, cursorKey)
+	if err != nil || v == "" {
+		return c, err
+	}
+	// Cursors saved before sub-second precision are accepted as well
+	c.since, err = time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return c, fmt.Errorf("invalid cursor %q: %w", v, err)
+	}
+	v, err = s.db.Cursor(ctx, cursorOffsetKey)
+	if err != nil || v == "" {
+		return c, err
+	}
+	c.offset, err = strconv.Atoi(v)
+	if err != nil {
+		return c, fmt.Errorf("invalid cursor offset %q: %w", v, err)
+	}
+	return c, nil
+}
+
+// saveCursor saves c in tx.
+func saveCursor(ctx context.Context, tx store.Tx, c indexCursor) error {
+	err := tx.SetCursor(ctx, cursorKey, c.since.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return err
+	}
+	return tx.SetCursor(ctx, cursorOffsetKey, strconv.Itoa(c.offset))
+}
+
+// advance returns the entries of a page read from c that have not been
+// queued yet, and the cursor just past them. entries must be the index's
+// answer to a request starting at c.since.
+func (c indexCursor) advance(entries []proxy.ModuleEntry) ([]proxy.ModuleEntry, indexCursor) {
+	skip := 0
+	for skip < len(entries) && skip < c.offset && entries[skip].Timestamp.Equal(c.since) {
+		skip++
+	}
+	fresh := entries[skip:]
+	if len(fresh) == 0 {
+		return nil, c
+	}
+	next := indexCursor{since: fresh[len(fresh)-1].Timestamp}
+	for _, e := range entries {
+		if e.Timestamp.Equal(next.since) {
+			next.offset++
+		}
+	}
+	return fresh, next
+}
+
+// queueIndex adds the modules listed in the index since the saved cursor to
+// the job queue, stopping once about limit entries have been read, and
+// reports whether it reached the end of the index. Each page of entries is
+// queued in the same transaction that advances the cursor, so the cursor only
+// moves past entries whose jobs have been recorded, and an entry is never
+// skipped, even if the scanner crashes halfway.
+func (s *Scanner) queueIndex(ctx context.Context, limit int64) (caughtUp bool, err error) {
+	cursor, err := s.loadCursor(ctx)
+	if err != nil {
+		return false, err
+	}
+	pageSize := cmp.Or(s.indexPage, modIndexLimit)
+	queued := int64(0)
+	for queued < limit {
+		// Ask for enough entries to get past those already queued. The index
+		// cannot be read past more than maxIndexLimit entries sharing one
+		// timestamp, and skipping the rest would lose modules, so the cursor
+		// stays put and the error is reported instead.
+		if cursor.offset >= maxIndexLimit {
+			return false, fmt.Errorf("%w: more than %d index entries share the timestamp %s", errIndexStuck, maxIndexLimit, cursor.since.Format(time.RFC3339Nano))
+		}
+		pageLimit := min(cursor.offset+pageSize, maxIndexLimit)
+		entries, err := s.index.Entries(ctx, cursor.since, pageLimit)
+		if err != nil {
+			return false, fmt.Errorf("failed to fetch index entries: %w", err)
+		}
+		fresh, next := cursor.advance(entries)
+		if len(fresh) == 0 {
+			return true, nil
+		}
+		now := time.Now()
+		// Once the index has been read, the page is queued even if the scanner
+		// is stopping
+		txCtx := context.Background()
+		err = s.db.InTx(txCtx, func(tx store.Tx) error {
+			for _, entry := range fresh {
+				version, ok := s.versions.jobVersion(entry)
+				if !ok {
+					continue
+				}
+				err := tx.AddJob(txCtx, entry.Path, version, now)
+				if err != nil {
+					return err
+				}
+			}
+			return saveCursor(txCtx, tx, next)
+		})
+		if err != nil {
+			return false, fmt.Errorf("failed to queue index entries: %w", err)
+		}
+		cursor = next
+		queued += int64(len(fresh))
+		log.Print(s.lFmt.Sprintf("Queued %d index entries up to %s", queued, cursor.since.Format(time.RFC3339Nano)))
+		if len(entries) < pageLimit {
+			return true, nil // The index has nothing newer
+		}
+	}
+	return false, nil
+}
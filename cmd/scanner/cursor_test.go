@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/fflewddur/pantry/proxy"
+	"github.com/fflewddur/pantry/proxy/proxytest"
+	"github.com/fflewddur/pantry/store"
+	"github.com/fflewddur/pantry/store/storetest"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+func TestCursorAdvance(t *testing.T) {
+	t0 := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Millisecond)
+	entry := func(path string, ts time.Time) proxy.ModuleEntry {
+		return proxy.ModuleEntry{Path: path, Version: "v1.0.0", Timestamp: ts}
+	}
+	page := []proxy.ModuleEntry{entry("a", t0), entry("b", t0), entry("c", t1), entry("d", t1)}
+
+	fresh, next := indexCursor{}.advance(page)
+	if len(fresh) != 4 || next != (indexCursor{since: t1, offset: 2}) {
+		t.Errorf("from the start: fresh = %v, next = %+v", fresh, next)
+	}
+	// a and b were queued by an earlier page that ended at t0
+	fresh, next = indexCursor{since: t0, offset: 2}.advance(page)
+	if len(fresh) != 2 || fresh[0].Path != "c" || next != (indexCursor{since: t1, offset: 2}) {
+		t.Errorf("after t0: fresh = %v, next = %+v", fresh, next)
+	}
+	// Only a was queued; the page ends with entries at t0, which must be counted
+	fresh, next = indexCursor{since: t0, offset: 1}.advance(page[:2])
+	if len(fresh) != 1 || fresh[0].Path != "b" || next != (indexCursor{since: t0, offset: 2}) {
+		t.Errorf("within t0: fresh = %v, next = %+v", fresh, next)
+	}
+	c := indexCursor{since: t1, offset: 2}
+	fresh, next = c.advance(page[2:])
+	if len(fresh) != 0 || next != c {
+		t.Errorf("caught up: fresh = %v, next = %+v", fresh, next)
+	}
+}
+
+// newIndexScanner returns a scanner reading the index at indexURL into a new
+// SQLite database, in pages of pageSize entries.
+func newIndexScanner(t *testing.T, indexURL string, pageSize int) *Scanner {
+	t.Helper()
+	return &Scanner{
+		db:        storetest.New(t),
+		index:     proxy.NewIndex(indexURL, nil),
+		indexPage: pageSize,
+		lFmt:      message.NewPrinter(language.English),
+	}
+}
+
+// publish adds n modules named prefix0, prefix1, ... to the index of srv, all
+// with timestamp ts.
+func publish(t *testing.T, srv *proxytest.Server, prefix string, n int, ts time.Time) {
+	t.Helper()
+	for i := range n {
+		err := srv.AddModule(fmt.Sprintf("example.com/%s%d", prefix, i), "v1.0.0", ts, map[string]string{"a.go": "package a\n"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// queuedPaths returns the paths of every pending job.
+func queuedPaths(t *testing.T, db store.Store) map[string]bool {
+	t.Helper()
+	jobs, err := db.JobsInState(context.Background(), store.JobPending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := make(map[string]bool)
+	for _, j := range jobs {
+		paths[j.Path] = true
+	}
+	return paths
+}
+
+func TestQueueIndexEqualTimestamps(t *testing.T) {
+	srv := proxytest.NewServer()
+	defer srv.Close()
+	t0 := time.Date(2025, 7, 1, 12, 0, 0, 123456789, time.UTC)
+	publish(t, srv, "a", 2, t0)
+	publish(t, srv, "b", 7, t0.Add(time.Nanosecond)) // Spans three pages of 3
+	publish(t, srv, "c", 2, t0.Add(time.Second))
+	s := newIndexScanner(t, srv.IndexURL(), 3)
+
+	caughtUp, err := s.queueIndex(context.Background(), 100)
+	if err != nil || !caughtUp {
+		t.Fatalf("queueIndex = %v, %v", caughtUp, err)
+	}
+	if queued := queuedPaths(t, s.db); len(queued) != 11 {
+		t.Errorf("queued %d modules, want 11: %v", len(queued), queued)
+	}
+	c, err := s.loadCursor(context.Background())
+	if err != nil || c != (indexCursor{since: t0.Add(time.Second), offset: 2}) {
+		t.Errorf("cursor = %+v, %v", c, err)
+	}
+
+	// Entries published later with the same timestamp as the cursor are
+	// still picked up, and nothing is queued twice
+	for _, path := range []string{"example.com/a0", "example.com/b3"} {
+		err := s.db.CompleteJob(context.Background(), path, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	publish(t, srv, "d", 1, t0.Add(time.Second))
+	_, err = s.queueIndex(context.Background(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	queued := queuedPaths(t, s.db)
+	if len(queued) != 10 || !queued["example.com/d0"] || queued["example.com/a0"] {
+		t.Errorf("after publishing d0: queued = %v", queued)
+	}
+}
+
+// crashingStore makes the nth AddJob fail, as if the scanner crashed while
+// queueing a page.
+type crashingStore struct {
+	store.Store
+	n int
+}
+
+type crashingTx struct {
+	store.Tx
+	s *crashingStore
+}
+
+func (c *crashingStore) InTx(ctx context.Context, fn func(store.Tx) error) error {
+	return c.Store.InTx(ctx, func(tx store.Tx) error {
+		return fn(&crashingTx{Tx: tx, s: c})
+	})
+}
+
+func (tx *crashingTx) AddJob(ctx context.Context, path, version string, now time.Time) error {
+	tx.s.n--
+	if tx.s.n == 0 {
+		return errors.New("crash")
+	}
+	return tx.Tx.AddJob(ctx, path, version, now)
+}
+
+func TestQueueIndexCrash(t *testing.T) {
+	srv := proxytest.NewServer()
+	defer srv.Close()
+	t0 := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	publish(t, srv, "a", 4, t0)
+	publish(t, srv, "b", 4, t0.Add(time.Millisecond))
+	s := newIndexScanner(t, srv.IndexURL(), 3)
+	db := s.db
+
+	// Crash on the second entry of the second page
+	s.db = &crashingStore{Store: db, n: 5}
+	_, err := s.queueIndex(context.Background(), 100)
+	if err == nil {
+		t.Fatal("queueIndex did not report the crash")
+	}
+	if queued := queuedPaths(t, db); len(queued) != 3 {
+		t.Errorf("after the crash: %d modules queued, want the 3 of the first page", len(queued))
+	}
+	c, err := s.loadCursor(context.Background())
+	if err != nil || c != (indexCursor{since: t0, offset: 3}) {
+		t.Errorf("after the crash: cursor = %+v, %v; want the end of the first page", c, err)
+	}
+
+	// Restart
+	s.db = db
+	_, err = s.queueIndex(context.Background(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queued := queuedPaths(t, db); len(queued) != 8 {
+		t.Errorf("after restarting: %d modules queued, want 8", len(queued))
+	}
+}
+
+// TestQueueIndexOverflow checks that entries sharing a timestamp beyond what
+// one index request can return are reported rather than skipped.
+func TestQueueIndexOverflow(t *testing.T) {
+	t0 := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	total := maxIndexLimit + 5
+	index := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil || limit > maxIndexLimit {
+			http.Error(w, "bad limit", http.StatusBadRequest)
+			return
+		}
+		for i := range min(limit, total) {
+			fmt.Fprintf(w, `{"Path": "example.com/m%d", "Version": "v1.0.0", "Timestamp": %q}`+"\n", i, t0.Format(time.RFC3339Nano))
+		}
+	}))
+	defer index.Close()
+	s := newIndexScanner(t, index.URL, 1500)
+
+	_, err := s.queueIndex(context.Background(), int64(total*2))
+	if !errors.Is(err, errIndexStuck) {
+		t.Fatalf("queueIndex: err = %v, want errIndexStuck", err)
+	}
+	if queued := queuedPaths(t, s.db); len(queued) != maxIndexLimit {
+		t.Errorf("%d modules queued, want %d", len(queued), maxIndexLimit)
+	}
+	want := indexCursor{since: t0, offset: maxIndexLimit}
+	if c, err := s.loadCursor(context.Background()); err != nil || c != want {
+		t.Errorf("cursor = %+v, %v; want %+v", c, err, want)
+	}
+	// Later runs keep failing without moving the cursor
+	_, err = s.queueIndex(context.Background(), int64(total*2))
+	if !errors.Is(err, errIndexStuck) {
+		t.Errorf("second queueIndex: err = %v, want errIndexStuck", err)
+	}
+	if c, err := s.loadCursor(context.Background()); err != nil || c != want {
+		t.Errorf("after the second run: cursor = %+v, %v; want %+v", c, err, want)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/fflewddur/pantry/store"
+)
+
+// maxRetryBackoff caps the delay between two attempts at a failed job.
+const maxRetryBackoff = 24 * time.Hour
+
+// drainJobs runs the queued jobs that are due through p until none are left,
+// until limit modules have been stored, or until ctx is cancelled. Each job is
+// attempted at most once per call. Jobs that have started when ctx is
+// cancelled are finished; the others stay queued.
+func (s *Scanner) drainJobs(ctx context.Context, p *pool, limit int64) error {
+	tried := make(map[string]bool)
+	for p.stored.Load() < limit && ctx.Err() == nil {
+		due, err := s.db.DueJobs(context.Background(), time.Now(), modIndexLimit)
+		if err != nil {
+			return fmt.Errorf("failed to read job queue: %w", err)
+		}
+		var batch []job
+		for _, j := range due {
+			next := job{path: j.Path, version: j.Version, attempts: j.Attempts}
+			if !tried[next.target()] {
+				tried[next.target()] = true
+				batch = append(batch, next)
+			}
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		jobs := make(chan job, s.workers)
+		go func() {
+			defer close(jobs)
+			for _, j := range batch {
+				if p.stored.Load() >= limit {
+					log.Print(s.lFmt.Sprintf("Reached maximum number of modules (%d). Stopping.", limit))
+					return
+				}
+				if ctx.Err() != nil {
+					log.Println("Stopping: waiting for in-flight modules to be stored.")
+					return
+				}
+				log.Printf("Processing module: %s", j.target())
+				if j.version == "" {
+					j.seenVersion = latestSeenVersion(j.path, s.db) // Check the latest version for this module path
+				}
+				jobs <- j
+			}
+		}()
+		p.run(jobs)
+	}
+	return nil
+}
+
+// finishJob records the outcome of a job: it leaves the queue if it
+// succeeded, and is otherwise retried with exponential backoff until it has
+// failed maxAttempts times.
+func (s *Scanner) finishJob(j job, jobErr error) {
+	ctx := context.Background()
+	if jobErr == nil {
+		err := s.db.CompleteJob(ctx, j.path, j.version)
+		if err != nil {
+			log.Printf("Error removing job for module %s: %v", j.target(), err)
+		}
+		return
+	}
+	attempts := j.attempts + 1
+	var next time.Time
+	if attempts < s.maxAttempts {
+		next = time.Now().Add(retryBackoff(s.retryBackoff, attempts))
+		log.Printf("Module %s failed %d times, retrying after %s", j.target(), attempts, next.Format(time.RFC3339))
+	} else {
+		log.Printf("Module %s failed %d times, giving up", j.target(), attempts)
+	}
+	err := s.db.FailJob(ctx, j.path, j.version, jobErr.Error(), next)
+	if err != nil {
+		log.Printf("Error recording failure of module %s: %v", j.target(), err)
+	}
+}
+
+// retryBackoff returns the delay before the next attempt at a job that has
+// failed attempts times: base, doubled for each further failure, up to
+// maxRetryBackoff.
+func retryBackoff(base time.Duration, attempts int) time.Duration {
+	d := base
+	for i := 1; i < attempts && d < maxRetryBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxRetryBackoff)
+}
+
+// ListJobs writes the jobs that failed and are waiting to be retried, then
+// those that were given up on, along with why their last attempt failed.
+func (s *Scanner) ListJobs(w io.Writer) (err error) {
+	defer func() {
+		err = errors.Join(err, s.db.Close())
+	}()
+	for _, state := range []string{store.JobFailed, store.JobDead} {
+		jobs, err := s.db.JobsInState(context.Background(), state)
+		if err != nil {
+			return err
+		}
+		for _, j := range jobs {
+			next := "-"
+			if j.State == store.JobFailed {
+				next = j.NextAttempt.Local().Format(time.RFC3339)
+			}
+			target := job{path: j.Path, version: j.Version}.target()
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", j.State, target, j.Attempts, next, j.LastError)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strings"
+
+	"github.com/fflewddur/pantry/extract"
+)
+
+// newLicensePolicy reads the policy from PANTRY_LICENSE_ALLOW and
+// PANTRY_LICENSE_DENY (comma-separated SPDX IDs) and PANTRY_LICENSE_UNKNOWN
+// ("deny", the default, or "allow"). PANTRY_LICENSE_ALLOW replaces
+// extract.DefaultAllowedLicenses.
+func newLicensePolicy() *extract.Policy {
+	allow := extract.DefaultAllowedLicenses
+	if v := os.Getenv("PANTRY_LICENSE_ALLOW"); v != "" {
+		allow = strings.Split(v, ",")
+	}
+	deny := strings.Split(os.Getenv("PANTRY_LICENSE_DENY"), ",")
+	allowUnknown := false
+	switch v := os.Getenv("PANTRY_LICENSE_UNKNOWN"); v {
+	case "", "deny":
+	case "allow":
+		allowUnknown = true
+	default:
+		log.Fatalf("Invalid value for PANTRY_LICENSE_UNKNOWN: %q", v)
+	}
+	return extract.NewPolicy(allow, deny, allowUnknown)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/fflewddur/pantry/extract"
+)
+
+func TestNewLicensePolicy(t *testing.T) {
+	t.Setenv("PANTRY_LICENSE_ALLOW", "")
+	t.Setenv("PANTRY_LICENSE_DENY", "")
+	t.Setenv("PANTRY_LICENSE_UNKNOWN", "")
+	p := newLicensePolicy()
+	if status, _ := p.Evaluate([]string{"MIT"}); status != extract.PolicyAllowed {
+		t.Errorf("default policy: MIT is %s", status)
+	}
+	if _, redistributable := p.Evaluate(nil); redistributable {
+		t.Error("default policy: modules without a license are redistributable")
+	}
+
+	t.Setenv("PANTRY_LICENSE_ALLOW", "MIT,GPL-3.0")
+	t.Setenv("PANTRY_LICENSE_DENY", "AGPL-3.0")
+	t.Setenv("PANTRY_LICENSE_UNKNOWN", "allow")
+	p = newLicensePolicy()
+	tests := map[string]string{
+		"GPL-3.0":    extract.PolicyAllowed,
+		"AGPL-3.0":   extract.PolicyDenied,
+		"Apache-2.0": extract.PolicyUnknown,
+	}
+	for id, want := range tests {
+		if status, _ := p.Evaluate([]string{id}); status != want {
+			t.Errorf("configured policy: %s is %s, want %s", id, status, want)
+		}
+	}
+	if _, redistributable := p.Evaluate(nil); !redistributable {
+		t.Error("configured policy: modules without a license are not redistributable")
+	}
+}
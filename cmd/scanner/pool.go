@@ -0,0 +1,89 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fflewddur/pantry/extract"
+)
+
+// job is a module path waiting to be resolved, downloaded, and parsed.
+type job struct {
+	path        string
+	version     string // Exact version to scan, or "" for the latest one
+	seenVersion string // Latest version already stored in the database, if any
+	attempts    int    // Number of earlier failed attempts
+}
+
+// target returns path@version, or just the path of a job for the latest
+// version.
+func (j job) target() string {
+	if j.version == "" {
+		return j.path
+	}
+	return j.path + "@" + j.version
+}
+
+// outcome is what a worker made of a job.
+type outcome struct {
+	job job
+	res *extract.Result // nil if the job was skipped or failed
+	err error
+}
+
+// pool fans jobs out to a fixed number of workers and funnels their results to
+// a single writer, so that downloads and parsing run in parallel while
+// database writes stay serialized.
+type pool struct {
+	workers int
+	work    func(job) (*extract.Result, error) // Returns a nil result if the job should be skipped
+	write   func(*extract.Result) error
+	finish  func(job, error) // Optional; called by the writer once a job is done, with the error if it failed
+
+	stored  atomic.Int64 // Modules successfully written
+	skipped atomic.Int64 // Modules that were already up to date
+	failed  atomic.Int64 // Modules that could not be fetched, parsed, or written
+}
+
+// run processes jobs until the channel is closed and every result has been written.
+func (p *pool) run(jobs <-chan job) {
+	results := make(chan outcome, p.workers)
+	var wg sync.WaitGroup
+	for range p.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				res, err := p.work(j)
+				results <- outcome{job: j, res: res, err: err}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	for o := range results {
+		err := o.err
+		switch {
+		case err != nil:
+			log.Printf("Error processing module %s: %v", o.job.target(), err)
+			p.failed.Add(1)
+		case o.res == nil:
+			p.skipped.Add(1)
+		default:
+			err = p.write(o.res)
+			if err != nil {
+				log.Printf("Error storing module %s: %v", o.job.target(), err)
+				p.failed.Add(1)
+			} else {
+				p.stored.Add(1)
+			}
+		}
+		if p.finish != nil {
+			p.finish(o.job, err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/fflewddur/pantry/extract"
+)
+
+// TestPool checks the pool's accounting; run with -race to also check that
+// writes are serialized and counters are updated safely.
+func TestPool(t *testing.T) {
+	const n = 200
+	var written []string // Only touched by the writer, so no lock is needed
+	finished := make(map[string]error)
+	p := &pool{
+		workers: 8,
+		work: func(j job) (*extract.Result, error) {
+			switch {
+			case strings.HasSuffix(j.path, "/skip"):
+				return nil, nil
+			case strings.HasSuffix(j.path, "/fail"):
+				return nil, errors.New("fetch failed")
+			}
+			return &extract.Result{Path: j.path}, nil
+		},
+		write: func(res *extract.Result) error {
+			if strings.HasSuffix(res.Path, "/badwrite") {
+				return errors.New("write failed")
+			}
+			written = append(written, res.Path)
+			return nil
+		},
+		finish: func(j job, err error) {
+			finished[j.path] = err
+		},
+	}
+
+	jobs := make(chan job)
+	go func() {
+		defer close(jobs)
+		suffixes := []string{"ok", "skip", "fail", "badwrite"}
+		for i := range n {
+			jobs <- job{path: fmt.Sprintf("example.com/m%d/%s", i, suffixes[i%len(suffixes)])}
+		}
+	}()
+	p.run(jobs)
+
+	if got, want := p.stored.Load(), int64(n/4); got != want {
+		t.Errorf("stored = %d, want %d", got, want)
+	}
+	if got, want := p.skipped.Load(), int64(n/4); got != want {
+		t.Errorf("skipped = %d, want %d", got, want)
+	}
+	if got, want := p.failed.Load(), int64(n/2); got != want {
+		t.Errorf("failed = %d, want %d", got, want)
+	}
+	if len(written) != n/4 {
+		t.Errorf("writer saw %d modules, want %d", len(written), n/4)
+	}
+	if len(finished) != n {
+		t.Errorf("%d jobs finished, want %d", len(finished), n)
+	}
+	for path, err := range finished {
+		failed := strings.HasSuffix(path, "/fail") || strings.HasSuffix(path, "/badwrite")
+		if failed != (err != nil) {
+			t.Errorf("job %s finished with err = %v", path, err)
+		}
+	}
+}
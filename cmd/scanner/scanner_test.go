@@ -1,9 +1,24 @@
 package main
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
 	"log"
 	"os"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/fflewddur/pantry/extract"
+	"github.com/fflewddur/pantry/proxy"
+	"github.com/fflewddur/pantry/proxy/proxytest"
+	"github.com/fflewddur/pantry/store"
+	"github.com/fflewddur/pantry/store/storetest"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
 )
 
 func TestEndToEnd(t *testing.T) {
@@ -17,6 +32,306 @@ func TestEndToEnd(t *testing.T) {
 		t.Fatal("Failed to create scanner")
 	}
 	log.Println("Starting end-to-end test for scanner...")
-	scanner.Start()
-	// TODO: Add a method to scan one specific URL with the scanner
+	err := scanner.Fetch("github.com/fflewddur/ltbsky@v0.3.0")
+	if err != nil {
+		t.Fatalf("Failed to fetch module: %v", err)
+	}
+}
+
+func TestParseModuleFromFakeProxy(t *testing.T) {
+	srv := proxytest.NewServer()
+	defer srv.Close()
+	modTime := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	err := srv.AddModule("example.com/hello", "v1.0.0", modTime, map[string]string{
+		"LICENSE":   mitLicense,
+		"README.md": "# hello\nSays hello.",
+		"hello.go":  "// Package hello says hello.\npackage hello\n\n// Hello returns a greeting.\nfunc Hello() string { return \"hello\" }\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := proxy.NewClient(srv.ProxyURL(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Scanner{proxy: client, extractor: &extract.Extractor{ScratchDir: t.TempDir()}}
+
+	info, err := s.proxy.Latest(context.Background(), "example.com/hello")
+	if err != nil {
+		t.Fatalf("Failed to resolve latest version: %v", err)
+	}
+	data, err := s.proxy.Zip(context.Background(), "example.com/hello", info.Version)
+	if err != nil {
+		t.Fatalf("Failed to download module: %v", err)
+	}
+	res, err := s.extractor.Extract("example.com/hello", info.Version, info.Time, data)
+	if err != nil {
+		t.Fatalf("Failed to parse module: %v", err)
+	}
+	if !strings.Contains(res.Readme, "Says hello.") {
+		t.Errorf("Readme = %q, want it to contain the README contents", res.Readme)
+	}
+}
+
+func TestStoreModuleSQLite(t *testing.T) {
+	db := storetest.New(t)
+	s := &Scanner{db: db, extractor: &extract.Extractor{ScratchDir: t.TempDir()}}
+	files := map[string]string{
+		"LICENSE":  mitLicense,
+		"go.mod":   "module example.com/hello\n\ngo 1.22\n\nrequire golang.org/x/text v0.3.0\n",
+		"hello.go": "// Package hello says hello.\npackage hello\n\nimport _ \"golang.org/x/text\"\n\n// Hello returns a greeting.\nfunc Hello() string { return \"hello\" }\n",
+	}
+	for _, version := range []string{"v1.1.0", "v1.0.0", "v1.1.0"} {
+		data := zipModule(t, "example.com/hello", version, files)
+		res, err := s.extractor.Extract("example.com/hello", version, time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC), data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = s.storeModule(res)
+		if err != nil {
+			t.Fatalf("storeModule(%s): %v", version, err)
+		}
+	}
+	if v := latestSeenVersion("example.com/hello", db); v != "v1.1.0" {
+		t.Errorf("latest version = %q, want v1.1.0", v)
+	}
+	err := s.Remove("example.com/hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStoreModuleLatest(t *testing.T) {
+	tests := []struct {
+		name    string
+		scanned []string // In the order they are scanned
+		want    string
+	}{
+		{"in order", []string{"v1.0.0", "v1.1.0"}, "v1.1.0"},
+		{"out of order", []string{"v1.1.0", "v1.0.0", "v1.0.1"}, "v1.1.0"},
+		{"rescan", []string{"v1.1.0", "v1.0.0", "v1.1.0"}, "v1.1.0"},
+		{"prerelease after release", []string{"v1.1.0", "v1.2.0-rc.1"}, "v1.1.0"},
+		{"release after prerelease", []string{"v1.2.0-rc.1", "v1.1.0"}, "v1.1.0"},
+		{"prereleases", []string{"v1.2.0-rc.2", "v1.2.0-rc.1"}, "v1.2.0-rc.2"},
+		{"pseudo after release", []string{"v1.1.0", "v1.1.1-0.20250701120000-0123456789ab"}, "v1.1.0"},
+		{"pseudo after prerelease", []string{"v1.2.0-rc.1", "v1.2.0-rc.1.0.20250701120000-0123456789ab"}, "v1.2.0-rc.1"},
+		{"pseudo-versions", []string{"v0.0.0-20250801120000-0123456789ab", "v0.0.0-20250701120000-0123456789ab"}, "v0.0.0-20250801120000-0123456789ab"},
+	}
+	files := map[string]string{
+		"LICENSE":  mitLicense,
+		"hello.go": "// Package hello says hello.\npackage hello\n",
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := storetest.New(t)
+			s := &Scanner{db: db, extractor: &extract.Extractor{ScratchDir: t.TempDir()}}
+			for _, version := range test.scanned {
+				data := zipModule(t, "example.com/hello", version, files)
+				res, err := s.extractor.Extract("example.com/hello", version, time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC), data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = s.storeModule(res)
+				if err != nil {
+					t.Fatalf("storeModule(%s): %v", version, err)
+				}
+			}
+			if v := latestSeenVersion("example.com/hello", db); v != test.want {
+				t.Errorf("latest version = %q, want %s", v, test.want)
+			}
+			var versions int
+			err := db.QueryRow(context.Background(), `SELECT COUNT(*) FROM modversions WHERE path = $1`, "example.com/hello").Scan(&versions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			distinct := make(map[string]bool)
+			for _, version := range test.scanned {
+				distinct[version] = true
+			}
+			if want := len(distinct); versions != want {
+				t.Errorf("stored %d versions, want %d", versions, want)
+			}
+		})
+	}
+}
+
+// zipModule returns the module zip the proxy would serve for files.
+func zipModule(t *testing.T, path, version string, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(path + "@" + version + "/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = io.WriteString(w, content)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := zw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+const mitLicense = `MIT License
+
+Copyright (c) 2025 Example Authors
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+`
+
+func TestJobQueue(t *testing.T) {
+	ctx := context.Background()
+	db := storetest.New(t)
+	srv := proxytest.NewServer()
+	defer srv.Close()
+	err := srv.AddModule("example.com/hello", "v1.0.0", time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC), map[string]string{
+		"LICENSE":  mitLicense,
+		"hello.go": "package hello\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := proxy.NewClient(srv.ProxyURL(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Scanner{
+		db:           db,
+		proxy:        client,
+		index:        proxy.NewIndex(srv.IndexURL(), nil),
+		workers:      2,
+		lFmt:         message.NewPrinter(language.English),
+		extractor:    &extract.Extractor{ScratchDir: t.TempDir()},
+		maxAttempts:  2,
+		retryBackoff: time.Hour,
+	}
+
+	caughtUp, err := s.queueIndex(ctx, 100)
+	if err != nil || !caughtUp {
+		t.Fatal(err)
+	}
+	// Listed in the index, but missing from the proxy
+	err = db.AddJob(ctx, "example.com/missing", "", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &pool{workers: s.workers, work: s.processJob, write: s.storeModule, finish: s.finishJob}
+	err = s.drainJobs(ctx, p, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.stored.Load() != 1 || p.failed.Load() != 1 {
+		t.Errorf("stored %d and failed %d modules, want 1 and 1", p.stored.Load(), p.failed.Load())
+	}
+	failed, err := db.JobsInState(ctx, store.JobFailed)
+	if err != nil || len(failed) != 1 || failed[0].Path != "example.com/missing" || failed[0].LastError == "" {
+		t.Fatalf("failed jobs = %v, %v", failed, err)
+	}
+	if until := time.Until(failed[0].NextAttempt); until < 50*time.Minute {
+		t.Errorf("failed job is retried in %v, want about an hour", until)
+	}
+	due, err := db.DueJobs(ctx, time.Now(), 10)
+	if err != nil || len(due) != 0 {
+		t.Errorf("due jobs after draining = %v, %v; want none", due, err)
+	}
+
+	s.finishJob(job{path: "example.com/missing", attempts: 1}, errors.New("still missing"))
+	dead, err := db.JobsInState(ctx, store.JobDead)
+	if err != nil || len(dead) != 1 || dead[0].LastError != "still missing" {
+		t.Errorf("dead jobs = %v, %v", dead, err)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{4, 8 * time.Minute},
+		{20, maxRetryBackoff},
+		{100, maxRetryBackoff},
+	}
+	for _, test := range tests {
+		if got := retryBackoff(time.Minute, test.attempts); got != test.want {
+			t.Errorf("retryBackoff(1m, %d) = %v, want %v", test.attempts, got, test.want)
+		}
+	}
+}
+
+func TestDaemon(t *testing.T) {
+	db := storetest.New(t)
+	srv := proxytest.NewServer()
+	defer srv.Close()
+	addModule := func(path string) {
+		err := srv.AddModule(path, "v1.0.0", time.Now(), map[string]string{"LICENSE": mitLicense, "a.go": "package a\n"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor := func(path string) {
+		deadline := time.Now().Add(10 * time.Second)
+		for latestSeenVersion(path, db) == "" {
+			if time.Now().After(deadline) {
+				t.Fatalf("daemon did not store %s", path)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	client, err := proxy.NewClient(srv.ProxyURL(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scratchDir := t.TempDir()
+	s := &Scanner{
+		db:           db,
+		proxy:        client,
+		index:        proxy.NewIndex(srv.IndexURL(), nil),
+		workers:      2,
+		lFmt:         message.NewPrinter(language.English),
+		scratchDir:   scratchDir,
+		extractor:    &extract.Extractor{ScratchDir: scratchDir},
+		maxAttempts:  2,
+		retryBackoff: time.Hour,
+	}
+
+	addModule("example.com/first")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Daemon(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+	waitFor("example.com/first")
+	// Published after the daemon caught up with the index
+	addModule("example.com/second")
+	waitFor("example.com/second")
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("daemon did not stop after its context was cancelled")
+	}
 }
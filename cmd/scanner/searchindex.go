@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fflewddur/pantry/extract"
+	"github.com/fflewddur/pantry/search"
+	"github.com/fflewddur/pantry/search/searchenv"
+	"github.com/fflewddur/pantry/store"
+)
+
+// newSearcher returns the search engine that scanned modules are written to,
+// or nil if the search indexes are rebuilt from the database instead.
+// Manticore is only written to when PANTRY_RT_INDEX names a real-time index
+// for modules; see searchenv.Open for the other settings.
+func newSearcher() (search.Searcher, error) {
+	tables := make(map[string]string)
+	if table := os.Getenv("PANTRY_RT_INDEX"); table != "" {
+		tables[search.Mods] = table
+	}
+	return searchenv.Open(tables)
+}
+
+// modDocument returns the search document for a module. Its fields match the
+// plain mods index in manticore.conf, so the server can query either.
+func modDocument(id int64, res *extract.Result) *search.Document {
+	return &search.Document{
+		ID: uint64(id),
+		Fields: map[string]string{
+			"path":     res.Path,
+			"version":  res.Version,
+			"readme":   res.Readme,
+			"docs":     res.Docs,
+			"licenses": strings.Join(res.Licenses, " "),
+		},
+		Attrs: map[string]any{
+			"time":            res.Time.Unix(),
+			"license":         res.PrimaryLicense,
+			"license_ids":     res.Licenses,
+			"policy_status":   res.PolicyStatus,
+			"redistributable": res.Redistributable,
+		},
+	}
+}
+
+// indexedSymbols returns the ids of the symbols of modPath that may be in the
+// search index: those of its current latest version and those of version,
+// which are about to be replaced.
+func indexedSymbols(tx store.Tx, modPath, version string) ([]int64, error) {
+	rows, err := tx.Query(context.Background(), `SELECT s.id FROM symbols s JOIN pkgs p ON p.path = s.pkg_path AND p.version = s.version
+	WHERE p.mod_path = $1 AND (p.version = $2 OR p.version = (SELECT version FROM mods WHERE path = $1))`, modPath, version)
+	if err != nil {
+		return nil, err
+	}
+	return store.CollectRows(rows, store.RowTo[int64])
+}
+
+// updateIndex replaces the search documents of a module and its symbols with
+// those of the version in res, stored as module id, which has just become its
+// latest version.
+func (s *Scanner) updateIndex(res *extract.Result, id int64, staleSymbols []int64) error {
+	ctx := context.Background()
+	err := s.searcher.Index(ctx, search.Mods, modDocument(id, res))
+	if err != nil {
+		return err
+	}
+	for _, id := range staleSymbols {
+		err := s.searcher.Delete(ctx, search.Symbols, uint64(id))
+		if err != nil {
+			return err
+		}
+	}
+	rows, err := s.db.Query(ctx, `SELECT s.id, s.pkg_path, s.name, s.kind, s.signature, COALESCE(s.synopsis, '') FROM symbols s
+	JOIN pkgs p ON p.path = s.pkg_path AND p.version = s.version WHERE p.mod_path = $1 AND p.version = $2`, res.Path, res.Version)
+	if err != nil {
+		return fmt.Errorf("failed to query symbols: %w", err)
+	}
+	docs, err := store.CollectRows(rows, func(row *sql.Rows) (*search.Document, error) {
+		var id int64
+		var pkgPath, name, kind, signature, synopsis string
+		err := row.Scan(&id, &pkgPath, &name, &kind, &signature, &synopsis)
+		return &search.Document{
+			ID: uint64(id),
+			Fields: map[string]string{
+				"pkg_path":  pkgPath,
+				"name":      name,
+				"kind":      kind,
+				"signature": signature,
+				"synopsis":  synopsis,
+			},
+		}, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query symbols: %w", err)
+	}
+	for _, doc := range docs {
+		err := s.searcher.Index(ctx, search.Symbols, doc)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
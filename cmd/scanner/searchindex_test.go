@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fflewddur/pantry/extract"
+)
+
+func TestModDocument(t *testing.T) {
+	res := &extract.Result{
+		Path:            "example.com/hello",
+		Version:         "v1.2.0",
+		Readme:          "Hello",
+		Time:            time.Unix(1700000000, 0),
+		Licenses:        []string{"MIT", "Apache-2.0"},
+		PrimaryLicense:  "MIT",
+		PolicyStatus:    extract.PolicyAllowed,
+		Redistributable: true,
+	}
+	doc := modDocument(42, res)
+	if doc.ID != 42 {
+		t.Errorf("ID = %d, want 42", doc.ID)
+	}
+	// Every field and attribute of the plain mods index must be present
+	for _, field := range []string{"path", "version", "readme", "docs", "licenses"} {
+		if _, ok := doc.Fields[field]; !ok {
+			t.Errorf("document is missing field %s", field)
+		}
+	}
+	for _, attr := range []string{"time", "license", "license_ids", "policy_status", "redistributable"} {
+		if _, ok := doc.Attrs[attr]; !ok {
+			t.Errorf("document is missing attribute %s", attr)
+		}
+	}
+	if doc.Attrs["time"] != int64(1700000000) || doc.Fields["licenses"] != "MIT Apache-2.0" {
+		t.Errorf("document = %+v", doc)
+	}
+}
+
+func TestNewSearcherDisabled(t *testing.T) {
+	t.Setenv("PANTRY_SEARCH", "")
+	t.Setenv("PANTRY_RT_INDEX", "")
+	if searcher, err := newSearcher(); searcher != nil || err != nil {
+		t.Errorf("newSearcher() = %v, %v; want nil when PANTRY_RT_INDEX is unset", searcher, err)
+	}
+}
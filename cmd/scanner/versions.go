@@ -0,0 +1,92 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strings"
+
+	"github.com/fflewddur/pantry/proxy"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// Kinds of versions, as named in PANTRY_VERSIONS.
+const (
+	kindRelease    = "release"    // For example v1.2.3 or v2.0.0+incompatible
+	kindPrerelease = "prerelease" // For example v1.3.0-rc.1
+	kindPseudo     = "pseudo"     // For example v0.0.0-20250701120000-0123456789ab
+)
+
+// versionKind returns the kind of the semantic version v.
+func versionKind(v string) string {
+	switch {
+	case module.IsPseudoVersion(v):
+		return kindPseudo
+	case semver.Prerelease(v) != "":
+		return kindPrerelease
+	default:
+		return kindRelease
+	}
+}
+
+// versionKinds selects the versions listed in the index that are scanned. A
+// nil set scans the latest version of each module listed instead, as
+// reported by the proxy.
+type versionKinds map[string]bool
+
+// newVersionKinds reads PANTRY_VERSIONS: "latest" (the default), or a
+// comma-separated list of the kinds of versions to scan exactly as they are
+// listed in the index: "release", "prerelease", and "pseudo".
+func newVersionKinds() versionKinds {
+	v := os.Getenv("PANTRY_VERSIONS")
+	if v == "" || v == "latest" {
+		return nil
+	}
+	kinds := make(versionKinds)
+	for _, kind := range strings.Split(v, ",") {
+		kind = strings.TrimSpace(kind)
+		switch kind {
+		case kindRelease, kindPrerelease, kindPseudo:
+			kinds[kind] = true
+		default:
+			log.Fatalf("Invalid value for PANTRY_VERSIONS: %q", v)
+		}
+	}
+	return kinds
+}
+
+// jobVersion returns the version to queue a job for when entry is read from
+// the index, "" meaning the latest version, and whether to queue one at all.
+func (k versionKinds) jobVersion(entry proxy.ModuleEntry) (string, bool) {
+	if k == nil {
+		return "", true
+	}
+	if !k[versionKind(entry.Version)] {
+		return "", false
+	}
+	return entry.Version, true
+}
+
+// newerVersion reports whether v should replace current as the latest
+// version of a module. Like the go command, it prefers releases to
+// prereleases, and prereleases to pseudo-versions, whatever their order.
+func newerVersion(v, current string) bool {
+	if current == "" {
+		return true
+	}
+	if rv, rc := kindRank(v), kindRank(current); rv != rc {
+		return rv > rc
+	}
+	return semver.Compare(v, current) >= 0
+}
+
+func kindRank(v string) int {
+	switch versionKind(v) {
+	case kindRelease:
+		return 2
+	case kindPrerelease:
+		return 1
+	default:
+		return 0
+	}
+}
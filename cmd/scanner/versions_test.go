@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fflewddur/pantry/extract"
+	"github.com/fflewddur/pantry/proxy"
+	"github.com/fflewddur/pantry/proxy/proxytest"
+)
+
+func TestVersionKind(t *testing.T) {
+	tests := map[string]string{
+		"v1.2.3":                               kindRelease,
+		"v2.0.0+incompatible":                  kindRelease,
+		"v1.3.0-rc.1":                          kindPrerelease,
+		"v0.0.0-20250701120000-0123456789ab":   kindPseudo,
+		"v1.2.4-0.20250701120000-0123456789ab": kindPseudo,
+	}
+	for v, want := range tests {
+		if got := versionKind(v); got != want {
+			t.Errorf("versionKind(%s) = %s, want %s", v, got, want)
+		}
+	}
+}
+
+func TestNewerVersion(t *testing.T) {
+	tests := []struct {
+		v, current string
+		want       bool
+	}{
+		{"v1.0.0", "", true},
+		{"v1.1.0", "v1.0.0", true},
+		{"v1.0.1", "v1.1.0", false},                               // Patch release on an old branch
+		{"v1.2.0-rc.1", "v1.1.0", false},                          // Prerelease of a newer version
+		{"v1.1.1-0.20250701120000-0123456789ab", "v1.1.0", false}, // Pseudo-version after a release
+		{"v1.1.0", "v1.2.0-rc.1", true},                           // Release after only prereleases
+		{"v0.0.0-20250801120000-0123456789ab", "v0.0.0-20250701120000-0123456789ab", true},
+	}
+	for _, test := range tests {
+		if got := newerVersion(test.v, test.current); got != test.want {
+			t.Errorf("newerVersion(%s, %s) = %v, want %v", test.v, test.current, got, test.want)
+		}
+	}
+}
+
+func TestNewVersionKinds(t *testing.T) {
+	t.Setenv("PANTRY_VERSIONS", "")
+	if kinds := newVersionKinds(); kinds != nil {
+		t.Errorf("default kinds = %v, want nil", kinds)
+	}
+	t.Setenv("PANTRY_VERSIONS", "release, pseudo")
+	kinds := newVersionKinds()
+	if len(kinds) != 2 || !kinds[kindRelease] || !kinds[kindPseudo] {
+		t.Errorf("kinds = %v", kinds)
+	}
+}
+
+// TestExactVersions scans every version listed in the index, including a
+// patch release on an old branch and a pseudo-version, but not a prerelease.
+func TestExactVersions(t *testing.T) {
+	ctx := context.Background()
+	srv := proxytest.NewServer()
+	defer srv.Close()
+	t0 := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	versions := []string{"v1.0.0", "v1.1.0", "v1.0.1", "v1.2.0-rc.1", "v1.1.1-0.20250701150000-0123456789ab"}
+	for i, v := range versions {
+		err := srv.AddModule("example.com/hello", v, t0.Add(time.Duration(i)*time.Hour), map[string]string{"LICENSE": mitLicense, "a.go": "package a\n"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	client, err := proxy.NewClient(srv.ProxyURL(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newIndexScanner(t, srv.IndexURL(), 0)
+	s.proxy = client
+	s.workers = 2
+	s.extractor = &extract.Extractor{ScratchDir: t.TempDir()}
+	s.maxAttempts = 1
+	s.versions = versionKinds{kindRelease: true, kindPseudo: true}
+
+	_, err = s.queueIndex(ctx, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &pool{workers: s.workers, work: s.processJob, write: s.storeModule, finish: s.finishJob}
+	err = s.drainJobs(ctx, p, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.stored.Load() != 4 || p.failed.Load() != 0 {
+		t.Errorf("stored %d and failed %d versions, want 4 and 0", p.stored.Load(), p.failed.Load())
+	}
+	for _, v := range versions {
+		stored, err := s.db.HasVersion(ctx, "example.com/hello", v)
+		if err != nil || stored != (v != "v1.2.0-rc.1") {
+			t.Errorf("HasVersion(%s) = %v, %v", v, stored, err)
+		}
+	}
+	if v := latestSeenVersion("example.com/hello", s.db); v != "v1.1.0" {
+		t.Errorf("latest version = %s, want v1.1.0", v)
+	}
+	if due, err := s.db.DueJobs(ctx, time.Now(), 10); err != nil || len(due) != 0 {
+		t.Errorf("jobs left = %v, %v", due, err)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// The JSON API mirrors the HTML pages under /api/v1. Field names are part of
+// the API and must not change within a version.
+
+func (s *Server) apiSearchHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received API request for search results")
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		writeJSONError(w, http.StatusBadRequest, "missing query parameter q")
+		return
+	}
+	page, perPage := pageParams(r)
+	searchResults, err := s.search(r.Context(), q, page, perPage)
+	if err != nil {
+		log.Printf("Error searching for %q: %v", q, err)
+		writeJSONError(w, http.StatusInternalServerError, "search failed")
+		return
+	}
+	writeJSON(w, http.StatusOK, searchResults)
+}
+
+// apiModHandler serves /api/v1/mod/<path>[@version].
+func (s *Server) apiModHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received API request for mod")
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	path := r.URL.Path[len("/api/v1/mod/"):]
+	path, version, _ := strings.Cut(path, "@")
+	if path == "" {
+		writeJSONError(w, http.StatusBadRequest, "missing module path")
+		return
+	}
+	modPageData, err := s.modPage(r.Context(), path, version)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			// /api/v1/mod/<path>/versions also lists versions, unless a
+			// module has that exact path
+			if modPath, ok := strings.CutSuffix(path, "/versions"); ok && version == "" {
+				s.writeVersions(w, r, modPath)
+				return
+			}
+			writeJSONError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		log.Printf("Error loading module %s: %v", path, err)
+		writeJSONError(w, http.StatusInternalServerError, "failed to load module")
+		return
+	}
+	writeJSON(w, http.StatusOK, modPageData)
+}
+
+// apiVersionsHandler serves /api/v1/versions/<path>. It has its own prefix
+// because a suffix after the module path could be part of another module's path.
+func (s *Server) apiVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received API request for versions")
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	path := r.URL.Path[len("/api/v1/versions/"):]
+	if path == "" {
+		writeJSONError(w, http.StatusBadRequest, "missing module path")
+		return
+	}
+	s.writeVersions(w, r, path)
+}
+
+// writeVersions responds with every known version of the module at path.
+func (s *Server) writeVersions(w http.ResponseWriter, r *http.Request, path string) {
+	versions, err := s.modVersions(r.Context(), path)
+	if err != nil {
+		log.Printf("Error querying versions for module %s: %v", path, err)
+		writeJSONError(w, http.StatusInternalServerError, "failed to load versions")
+		return
+	}
+	if len(versions) == 0 {
+		writeJSONError(w, http.StatusNotFound, "module "+path+": not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, &VersionsResponse{Path: path, Versions: versions})
+}
+
+type VersionsResponse struct {
+	Path     string        `json:"path"`
+	Versions []*ModVersion `json:"versions"` // Newest first
+}
+
+// APIError is the body of every non-2xx API response.
+type APIError struct {
+	Status int    `json:"status"`
+	Error  string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		log.Printf("Error writing response: %v", err)
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, &APIError{Status: status, Error: msg})
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fflewddur/pantry/search/embedded"
+	"github.com/fflewddur/pantry/store"
+	"github.com/fflewddur/pantry/store/storetest"
+)
+
+// TestAPIErrors checks the requests rejected before the database or search
+// engine is consulted.
+func TestAPIErrors(t *testing.T) {
+	s := &Server{}
+	tests := []struct {
+		method  string
+		url     string
+		handler http.HandlerFunc
+		status  int
+	}{
+		{http.MethodGet, "/api/v1/search", s.apiSearchHandler, http.StatusBadRequest},
+		{http.MethodGet, "/api/v1/search?q=+", s.apiSearchHandler, http.StatusBadRequest},
+		{http.MethodPost, "/api/v1/search?q=json", s.apiSearchHandler, http.StatusMethodNotAllowed},
+		{http.MethodGet, "/api/v1/mod/", s.apiModHandler, http.StatusBadRequest},
+		{http.MethodDelete, "/api/v1/mod/example.com/m", s.apiModHandler, http.StatusMethodNotAllowed},
+		{http.MethodGet, "/api/v1/versions/", s.apiVersionsHandler, http.StatusBadRequest},
+		{http.MethodPost, "/api/v1/versions/example.com/m", s.apiVersionsHandler, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		tt.handler(rec, httptest.NewRequest(tt.method, tt.url, nil))
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: status = %d, want %d", tt.method, tt.url, rec.Code, tt.status)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+			t.Errorf("%s %s: Content-Type = %q", tt.method, tt.url, ct)
+		}
+		var body APIError
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Errorf("%s %s: invalid error body %q: %v", tt.method, tt.url, rec.Body, err)
+		}
+		if body.Status != tt.status || body.Error == "" {
+			t.Errorf("%s %s: error body = %+v", tt.method, tt.url, body)
+		}
+	}
+}
+
+// TestAPIVersionsRoute checks that a module whose path ends in /versions is
+// not mistaken for the version list of its parent, and that the /versions
+// suffix lists versions when no module has that path.
+func TestAPIVersionsRoute(t *testing.T) {
+	ctx := context.Background()
+	db := storetest.New(t)
+	for _, m := range []*store.Module{
+		{Path: "example.com/x", Version: "v1.0.0"},
+		{Path: "example.com/x", Version: "v1.1.0"},
+		{Path: "example.com/x/versions", Version: "v0.1.0"},
+		{Path: "example.com/y", Version: "v2.0.0"},
+	} {
+		m.Time = time.Now()
+		err := db.InTx(ctx, func(tx store.Tx) error {
+			err := tx.PutVersion(ctx, m)
+			if err != nil {
+				return err
+			}
+			return tx.PutModule(ctx, m)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := &Server{db: db}
+
+	rec := httptest.NewRecorder()
+	s.apiModHandler(rec, httptest.NewRequest(http.MethodGet, "/api/v1/mod/example.com/x/versions", nil))
+	var mod ModPageData
+	if err := json.Unmarshal(rec.Body.Bytes(), &mod); err != nil || mod.Path != "example.com/x/versions" || mod.Version != "v0.1.0" {
+		t.Errorf("module ending in /versions: status = %d, body = %s", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	s.apiVersionsHandler(rec, httptest.NewRequest(http.MethodGet, "/api/v1/versions/example.com/x", nil))
+	var versions VersionsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &versions); err != nil || versions.Path != "example.com/x" || len(versions.Versions) != 2 || versions.Versions[0].Version != "v1.1.0" {
+		t.Errorf("versions: status = %d, body = %s", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	s.apiVersionsHandler(rec, httptest.NewRequest(http.MethodGet, "/api/v1/versions/example.com/unknown", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown module: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	rec = httptest.NewRecorder()
+	s.apiModHandler(rec, httptest.NewRequest(http.MethodGet, "/api/v1/mod/example.com/y/versions", nil))
+	versions = VersionsResponse{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &versions); err != nil || versions.Path != "example.com/y" || len(versions.Versions) != 1 {
+		t.Errorf("versions suffix: status = %d, body = %s", rec.Code, rec.Body)
+	}
+	rec = httptest.NewRecorder()
+	s.apiModHandler(rec, httptest.NewRequest(http.MethodGet, "/api/v1/mod/example.com/unknown/versions", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("versions suffix of an unknown module: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+// TestAPISearchNoResults checks that a search without matches still has its
+// results field, as an empty list.
+func TestAPISearchNoResults(t *testing.T) {
+	searcher, err := embedded.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{db: storetest.New(t), searcher: searcher}
+	rec := httptest.NewRecorder()
+	s.apiSearchHandler(rec, httptest.NewRequest(http.MethodGet, "/api/v1/search?q=nothing", nil))
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if string(body["results"]) != "[]" {
+		t.Errorf("results = %s, want []", body["results"])
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fflewddur/pantry/extract"
+	"github.com/fflewddur/pantry/store"
+)
+
+// modLicenses fills in the licenses detected in the module version shown on a mod page.
+func (s *Server) modLicenses(ctx context.Context, page *ModPageData) error {
+	rows, err := s.db.Query(ctx, `SELECT license, file, confidence FROM modlicenses
+	WHERE path = $1 AND version = $2 ORDER BY license, confidence DESC, file`, page.Path, page.Version)
+	if err != nil {
+		return err
+	}
+	page.Licenses, err = store.CollectRows(rows, store.RowToAddrOfStructByPos[ModLicense])
+	if err != nil {
+		return err
+	}
+	matches := make([]extract.LicenseMatch, len(page.Licenses))
+	for i, l := range page.Licenses {
+		matches[i] = extract.LicenseMatch{License: l.License, File: l.File, Confidence: l.Confidence}
+	}
+	page.License = extract.PrimaryLicense(matches)
+	return nil
+}
+
+// modPolicy returns the license policy status of path@version and whether its
+// README and documentation may be shown.
+func (s *Server) modPolicy(ctx context.Context, path, version string) (string, bool, error) {
+	var status sql.NullString
+	var redistributable sql.NullBool
+	err := s.db.QueryRow(ctx, "SELECT policy_status, redistributable FROM modversions WHERE path = $1 AND version = $2", path, version).Scan(&status, &redistributable)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", false, err
+	}
+	return status.String, isRedistributable(redistributable), nil
+}
+
+// isRedistributable interprets a stored redistributable flag. Versions
+// scanned before the license policy existed have no flag and stay visible
+// until they are scanned again.
+func isRedistributable(redistributable sql.NullBool) bool {
+	return !redistributable.Valid || redistributable.Bool
+}
+
+type ModLicense struct {
+	License    string  `json:"license"` // SPDX ID
+	File       string  `json:"file"`
+	Confidence float32 `json:"confidence"`
+}
+
+// Percent formats the match confidence for display.
+func (l *ModLicense) Percent() string {
+	return fmt.Sprintf("%.0f%%", l.Confidence*100)
+}
+
+// Accepted reports whether the match is confident enough to count.
+func (l *ModLicense) Accepted() bool {
+	return l.Confidence >= extract.LicenseConfidenceThreshold
+}
+
+const maxLicenseFacets = 20 // Number of licenses counted on a results page
+
+// queryFilters maps the filter prefixes accepted in module searches to the
+// search index attributes they filter on.
+var queryFilters = map[string]string{
+	"license": "license_ids",   // Any detected license, e.g. license:MIT
+	"policy":  "policy_status", // License policy status, e.g. policy:allowed
+}
+
+// attrFilters splits name:value and name:-value terms out of a search query.
+// It returns the remaining full-text query along with the values that
+// results must have and those they must not have.
+func attrFilters(q, name string) (text string, include, exclude []string) {
+	var words []string
+	for _, word := range strings.Fields(q) {
+		id, ok := strings.CutPrefix(word, name+":")
+		if !ok || id == "" || id == "-" {
+			words = append(words, word)
+			continue
+		}
+		if id, ok := strings.CutPrefix(id, "-"); ok {
+			exclude = append(exclude, id)
+		} else {
+			include = append(include, id)
+		}
+	}
+	return strings.Join(words, " "), include, exclude
+}
+
+// mapAppend appends values to the slice stored under key in *m, creating the map if needed.
+func mapAppend(m *map[string][]string, key string, values []string) {
+	if *m == nil {
+		*m = make(map[string][]string)
+	}
+	(*m)[key] = append((*m)[key], values...)
+}
+
+// Facet is the number of search matches sharing one attribute value.
+type Facet struct {
+	Value string `json:"value"` // Empty for modules without a detected license
+	Count int    `json:"count"`
+}
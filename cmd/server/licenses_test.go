@@ -0,0 +1,27 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAttrFilters(t *testing.T) {
+	tests := []struct {
+		q                string
+		text             string
+		include, exclude []string
+	}{
+		{"http router", "http router", nil, nil},
+		{"http router license:MIT", "http router", []string{"MIT"}, nil},
+		{"license:MIT license:Apache-2.0 yaml", "yaml", []string{"MIT", "Apache-2.0"}, nil},
+		{"yaml license:-GPL-3.0 license:-AGPL-3.0", "yaml", nil, []string{"GPL-3.0", "AGPL-3.0"}},
+		{"yaml license: license:-", "yaml license: license:-", nil, nil},
+		{"yaml policy:allowed", "yaml policy:allowed", nil, nil},
+	}
+	for _, tt := range tests {
+		text, include, exclude := attrFilters(tt.q, "license")
+		if text != tt.text || !reflect.DeepEqual(include, tt.include) || !reflect.DeepEqual(exclude, tt.exclude) {
+			t.Errorf("attrFilters(%q) = %q, %q, %q; want %q, %q, %q", tt.q, text, include, exclude, tt.text, tt.include, tt.exclude)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fflewddur/pantry/search"
+	"github.com/fflewddur/pantry/store"
+	"golang.org/x/mod/module"
+)
+
+// MajorVersion is one major version of a module, such as example.com/foo/v2
+// or gopkg.in/yaml.v3.
+type MajorVersion struct {
+	ID    uint64 `json:"-"`
+	Path  string `json:"path"`
+	Major int    `json:"major"` // 1 for paths without a major version suffix
+}
+
+// splitMajor returns the path shared by every major version of the module at
+// path, and the major version of path. gopkg.in paths keep their prefix, so
+// that gopkg.in/yaml.v2 and gopkg.in/yaml.v3 share gopkg.in/yaml.
+func splitMajor(path string) (prefix string, major int) {
+	prefix, pathMajor, ok := module.SplitPathVersion(path)
+	if !ok || pathMajor == "" {
+		return path, 1
+	}
+	major, err := strconv.Atoi(strings.TrimPrefix(pathMajor[1:], "v"))
+	if err != nil {
+		return path, 1
+	}
+	return prefix, major
+}
+
+// majorVersions returns the stored major versions of the module at path,
+// newest first, including path itself if it is stored.
+func (s *Server) majorVersions(ctx context.Context, path string) ([]*MajorVersion, error) {
+	prefix, _ := splitMajor(path)
+	// LIKE also matches paths that merely share the prefix, such as
+	// example.com/foo/vendor, so every candidate is checked with splitMajor
+	rows, err := s.db.Query(ctx, `SELECT id, path FROM mods WHERE path = $1 OR path LIKE $2 OR path LIKE $3`, prefix, prefix+"/v%", prefix+".v%")
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := store.CollectRows(rows, func(row *sql.Rows) (*MajorVersion, error) {
+		mv := &MajorVersion{}
+		err := row.Scan(&mv.ID, &mv.Path)
+		return mv, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	var majors []*MajorVersion
+	for _, mv := range candidates {
+		var p string
+		p, mv.Major = splitMajor(mv.Path)
+		if p == prefix {
+			majors = append(majors, mv)
+		}
+	}
+	sort.Slice(majors, func(i, j int) bool {
+		return majors[i].Major > majors[j].Major
+	})
+	return majors, nil
+}
+
+// modMajors fills in the other major versions of the module shown on a mod
+// page, and the latest one if it is newer.
+func (s *Server) modMajors(ctx context.Context, page *ModPageData) error {
+	majors, err := s.majorVersions(ctx, page.Path)
+	if err != nil || len(majors) < 2 {
+		return err
+	}
+	page.Majors = majors
+	if _, major := splitMajor(page.Path); majors[0].Major > major {
+		page.NewerMajor = majors[0].Path
+	}
+	return nil
+}
+
+// majorGroup is one search result standing for every major version of a
+// module that matched the query.
+type majorGroup struct {
+	Hit    search.Hit      // The latest matching major version, with the best score of the group
+	Majors []*MajorVersion // Every matching major version, newest first
+}
+
+// groupMajors groups hits, ordered by score, by module regardless of major
+// version, and places each group at the rank of its best match. paths holds
+// the path of each hit; hits missing from it are left out. Only versions that
+// matched, and so passed the query's filters, are grouped: a newer major
+// version that did not match never replaces one that did.
+func groupMajors(hits []search.Hit, paths map[uint64]string) []*majorGroup {
+	var groups []*majorGroup
+	byPrefix := make(map[string]*majorGroup)
+	for _, hit := range hits {
+		path, ok := paths[hit.ID]
+		if !ok {
+			log.Printf("Module with ID %d not found in database", hit.ID)
+			continue
+		}
+		prefix, major := splitMajor(path)
+		g := byPrefix[prefix]
+		if g == nil {
+			g = &majorGroup{Hit: hit}
+			byPrefix[prefix] = g
+			groups = append(groups, g)
+		}
+		g.Majors = append(g.Majors, &MajorVersion{ID: hit.ID, Path: path, Major: major})
+	}
+	for _, g := range groups {
+		sort.SliceStable(g.Majors, func(i, j int) bool {
+			return g.Majors[i].Major > g.Majors[j].Major
+		})
+		g.Hit.ID = g.Majors[0].ID
+	}
+	return groups
+}
+
+// modPaths returns the paths of the modules with the given ids, in one query.
+func (s *Server) modPaths(ctx context.Context, ids []uint64) (map[uint64]string, error) {
+	paths := make(map[uint64]string, len(ids))
+	if len(ids) == 0 {
+		return paths, nil
+	}
+	placeholders := make([]string, len(ids))
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "$" + strconv.Itoa(i+1)
+		args[i] = id
+	}
+	rows, err := s.db.Query(ctx, `SELECT id, path FROM mods WHERE id IN (`+strings.Join(placeholders, ", ")+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	mods, err := store.CollectRows(rows, func(row *sql.Rows) (*MajorVersion, error) {
+		mv := &MajorVersion{}
+		err := row.Scan(&mv.ID, &mv.Path)
+		return mv, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, mv := range mods {
+		paths[mv.ID] = mv.Path
+	}
+	return paths, nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"html/template"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fflewddur/pantry/search"
+	"github.com/fflewddur/pantry/search/embedded"
+	"github.com/fflewddur/pantry/store"
+	"github.com/fflewddur/pantry/store/storetest"
+)
+
+func TestSplitMajor(t *testing.T) {
+	tests := []struct {
+		path   string
+		prefix string
+		major  int
+	}{
+		{"example.com/foo", "example.com/foo", 1},
+		{"example.com/foo/v2", "example.com/foo", 2},
+		{"example.com/foo/v12", "example.com/foo", 12},
+		{"example.com/foo/vendor", "example.com/foo/vendor", 1},
+		{"gopkg.in/yaml.v3", "gopkg.in/yaml", 3},
+		{"gopkg.in/check.v1", "gopkg.in/check", 1},
+	}
+	for _, test := range tests {
+		prefix, major := splitMajor(test.path)
+		if prefix != test.prefix || major != test.major {
+			t.Errorf("splitMajor(%s) = %s, %d; want %s, %d", test.path, prefix, major, test.prefix, test.major)
+		}
+	}
+}
+
+func TestMajorVersionsSQLite(t *testing.T) {
+	ctx := context.Background()
+	db := storetest.New(t)
+	ids := make(map[string]uint64)
+	for _, path := range []string{"example.com/foo", "example.com/foo/v3", "example.com/foo/v2", "example.com/foobar", "example.com/foo/vendor", "gopkg.in/yaml.v2", "gopkg.in/yaml.v3"} {
+		m := &store.Module{Path: path, Version: "v1.0.0", Time: time.Now()}
+		err := db.InTx(ctx, func(tx store.Tx) error {
+			err := tx.PutVersion(ctx, m)
+			if err != nil {
+				return err
+			}
+			return tx.PutModule(ctx, m)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[path] = uint64(m.ID)
+	}
+	s := &Server{db: db}
+
+	majors, err := s.majorVersions(ctx, "example.com/foo/v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, mv := range majors {
+		paths = append(paths, mv.Path)
+	}
+	if got := strings.Join(paths, " "); got != "example.com/foo/v3 example.com/foo/v2 example.com/foo" {
+		t.Errorf("major versions = %s", got)
+	}
+
+	page, err := s.modPage(ctx, "example.com/foo", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.NewerMajor != "example.com/foo/v3" || len(page.Majors) != 3 {
+		t.Errorf("v1 page: newer major = %q, majors = %d", page.NewerMajor, len(page.Majors))
+	}
+	tmpl, err := template.New("mod.html").ParseFiles("../../templates/mod.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, page); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), `A newer major version of this module is available:
+      <a href="/mod/example.com/foo/v3">`) {
+		t.Errorf("v1 page has no link to v3:\n%s", b.String())
+	}
+	page, err = s.modPage(ctx, "gopkg.in/yaml.v3", "")
+	if err != nil || page.NewerMajor != "" || len(page.Majors) != 2 {
+		t.Errorf("gopkg.in/yaml.v3 page: newer major = %q, majors = %d, err = %v", page.NewerMajor, len(page.Majors), err)
+	}
+
+	// Every module but gopkg.in/yaml is about routers, and foo/v3 also has
+	// a license that searches can exclude, besides its primary license
+	searcher, err := embedded.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, id := range ids {
+		licenses := []string{"MIT"}
+		if path == "example.com/foo/v3" {
+			licenses = append(licenses, "GPL-3.0")
+		}
+		text := "router"
+		if strings.HasPrefix(path, "gopkg.in/") {
+			text = "yaml"
+		}
+		err := searcher.Index(ctx, search.Mods, &search.Document{ID: id, Fields: map[string]string{"path": path, "readme": text}, Attrs: map[string]any{"license": "MIT", "license_ids": licenses}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.searcher = searcher
+	tests := []struct {
+		q     string
+		page  int
+		want  string // Path of the only result, followed by its other majors
+		total int
+		next  int
+	}{
+		{"router", 1, "example.com/foo/v3 example.com/foo/v2 example.com/foo", 3, 2},
+		{"router license:-GPL-3.0", 1, "example.com/foo/v2 example.com/foo", 3, 2},
+		{"router license:-GPL-3.0", 3, "example.com/foo/vendor", 3, 0},
+		{"yaml", 1, "gopkg.in/yaml.v3 gopkg.in/yaml.v2", 1, 0},
+	}
+	for _, test := range tests {
+		res, err := s.search(ctx, test.q, test.page, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got string
+		for _, m := range res.Results {
+			got = strings.Join(append([]string{m.Path}, m.OtherMajors...), " ")
+		}
+		if len(res.Results) != 1 || got != test.want || res.Total != test.total || res.NextPage != test.next {
+			t.Errorf("search(%q) page %d = %q, total %d, next page %d; want %q, total %d, next page %d", test.q, test.page, got, res.Total, res.NextPage, test.want, test.total, test.next)
+		}
+	}
+}
+
+func TestGroupMajors(t *testing.T) {
+	hits := []search.Hit{{ID: 1, Score: 3}, {ID: 2, Score: 2}, {ID: 3, Score: 1}, {ID: 4, Score: 0.5}}
+	paths := map[uint64]string{1: "example.com/foo", 2: "example.com/foobar", 3: "example.com/foo/v2"}
+	groups := groupMajors(hits, paths)
+	if len(groups) != 2 {
+		t.Fatalf("%d groups, want 2", len(groups))
+	}
+	// foo/v2 takes the place of foo, with its score
+	if groups[0].Hit != (search.Hit{ID: 3, Score: 3}) || len(groups[0].Majors) != 2 || groups[0].Majors[1].Path != "example.com/foo" {
+		t.Errorf("first group = %+v", groups[0])
+	}
+	if groups[1].Hit != (search.Hit{ID: 2, Score: 2}) || len(groups[1].Majors) != 1 {
+		t.Errorf("second group = %+v", groups[1])
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/fflewddur/pantry/store"
+)
+
+// pkgHandler serves the documentation of a single package at /pkg/<import path>[@version].
+func (s *Server) pkgHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Received request for pkg page")
+	path, version, _ := strings.Cut(r.URL.Path[len("/pkg/"):], "@")
+	ctx := r.Context()
+	data := &PkgPageData{Path: path}
+
+	// When nested modules share a path prefix, the package belongs to the longest module path
+	var err error
+	if version == "" {
+		err = s.db.QueryRow(ctx, `SELECT p.mod_path, p.version, p.name, COALESCE(p.synopsis, ''), COALESCE(p.doc, '')
+		FROM pkgs p JOIN mods m ON m.path = p.mod_path AND m.version = p.version
+		WHERE p.path = $1 ORDER BY length(p.mod_path) DESC LIMIT 1`, path).Scan(&data.ModPath, &data.Version, &data.Name, &data.Synopsis, &data.Doc)
+	} else {
+		err = s.db.QueryRow(ctx, `SELECT mod_path, version, name, COALESCE(synopsis, ''), COALESCE(doc, '')
+		FROM pkgs WHERE path = $1 AND version = $2 ORDER BY length(mod_path) DESC LIMIT 1`, path, version).Scan(&data.ModPath, &data.Version, &data.Name, &data.Synopsis, &data.Doc)
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Package %s not found", r.URL.Path[len("/pkg/"):])
+			http.NotFound(w, r)
+			return
+		}
+		log.Printf("Error querying database for package %s: %v", path, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	err = s.db.QueryRow(ctx, "SELECT version FROM mods WHERE path = $1", data.ModPath).Scan(&data.Latest)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Error querying latest version of module %s: %v", data.ModPath, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	_, data.Redistributable, err = s.modPolicy(ctx, data.ModPath, data.Version)
+	if err != nil {
+		log.Printf("Error querying license policy of module %s@%s: %v", data.ModPath, data.Version, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := s.db.Query(ctx, `SELECT name, kind, parent, signature, COALESCE(doc, '') FROM symbols
+	WHERE pkg_path = $1 AND version = $2 ORDER BY name`, path, data.Version)
+	if err != nil {
+		log.Printf("Error querying symbols for package %s@%s: %v", path, data.Version, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	symbols, err := store.CollectRows(rows, store.RowToAddrOfStructByPos[PkgSymbol])
+	if err != nil {
+		log.Printf("Error reading symbols for package %s@%s: %v", path, data.Version, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if data.Redistributable {
+		data.groupSymbols(symbols)
+	} else {
+		data.Synopsis, data.Doc = "", ""
+	}
+
+	pkgs, err := s.modPackages(ctx, data.ModPath, data.Version)
+	if err != nil {
+		log.Printf("Error querying packages for module %s@%s: %v", data.ModPath, data.Version, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	for _, p := range pkgs {
+		if !data.Redistributable {
+			p.Synopsis = ""
+		}
+		if strings.HasPrefix(p.Path, path+"/") {
+			data.Subdirs = append(data.Subdirs, p)
+		}
+	}
+
+	tmpl, err := template.New("pkg.html").ParseFiles("templates/pkg.html")
+	if err != nil {
+		log.Printf("Error parsing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	err = tmpl.Execute(w, data)
+	if err != nil {
+		log.Printf("Error writing response: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// groupSymbols sorts symbols into the doc sections of a package page, the way
+// go doc does: package-level constants, variables, and functions, then each type
+// followed by its own constants, variables, constructors, and methods.
+// Constants and variables declared together are shown as one declaration.
+func (page *PkgPageData) groupSymbols(symbols []*PkgSymbol) {
+	types := make(map[string]*PkgType)
+	for _, sym := range symbols {
+		if sym.Kind == "type" {
+			t := &PkgType{PkgDecl: PkgDecl{Names: []string{sym.Name}, Signature: sym.Signature, Doc: sym.Doc}}
+			types[sym.Name] = t
+			page.Types = append(page.Types, t)
+		}
+	}
+	for _, sym := range symbols {
+		if sym.Kind == "type" {
+			continue
+		}
+		var consts, vars, funcs *[]*PkgDecl
+		if t := types[sym.Parent]; t != nil {
+			consts, vars, funcs = &t.Consts, &t.Vars, &t.Funcs
+			if sym.Kind == "method" {
+				funcs = &t.Methods
+			}
+		} else {
+			consts, vars, funcs = &page.Consts, &page.Vars, &page.Funcs
+		}
+		switch sym.Kind {
+		case "const":
+			*consts = addDecl(*consts, sym)
+		case "var":
+			*vars = addDecl(*vars, sym)
+		default:
+			*funcs = append(*funcs, &PkgDecl{Names: []string{sym.Name}, Signature: sym.Signature, Doc: sym.Doc})
+		}
+	}
+}
+
+// addDecl adds sym to decls, merging it into an existing declaration with the same source.
+func addDecl(decls []*PkgDecl, sym *PkgSymbol) []*PkgDecl {
+	for _, d := range decls {
+		if d.Signature == sym.Signature {
+			d.Names = append(d.Names, sym.Name)
+			return decls
+		}
+	}
+	return append(decls, &PkgDecl{Names: []string{sym.Name}, Signature: sym.Signature, Doc: sym.Doc})
+}
+
+type PkgPageData struct {
+	Path     string
+	Name     string
+	Synopsis string
+	Doc      string
+	ModPath  string // Module containing the package
+	Version  string // The module version being viewed
+	Latest   string // The latest known version of the module
+
+	Redistributable bool // If false, the documentation is withheld
+
+	Consts []*PkgDecl
+	Vars   []*PkgDecl
+	Funcs  []*PkgDecl
+	Types  []*PkgType
+
+	Subdirs []*PkgSummary // Packages nested below this one in the same module
+}
+
+// PkgDecl is one declaration on a package page; grouped constants and
+// variables have several names.
+type PkgDecl struct {
+	Names     []string
+	Signature string
+	Doc       string
+}
+
+// Anchor is the fragment identifier linking to the declaration. Each name of
+// grouped constants and variables also has an anchor of its own.
+func (d *PkgDecl) Anchor() string {
+	return d.Names[0]
+}
+
+type PkgType struct {
+	PkgDecl
+	Consts  []*PkgDecl
+	Vars    []*PkgDecl
+	Funcs   []*PkgDecl // Constructors returning the type
+	Methods []*PkgDecl
+}
+
+type PkgSymbol struct {
+	Name      string
+	Kind      string
+	Parent    string
+	Signature string
+	Doc       string
+}
+
+type PkgSummary struct {
+	Path     string `json:"path"`
+	Name     string `json:"name"`
+	Synopsis string `json:"synopsis"`
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fflewddur/pantry/extract"
+)
+
+func TestGroupSymbols(t *testing.T) {
+	symbols := []*PkgSymbol{
+		{Name: "Greeter.Greet", Kind: "method", Parent: "Greeter", Signature: "func (g *Greeter) Greet() string"},
+		{Name: "Greeter", Kind: "type", Signature: "type Greeter struct{}"},
+		{Name: "Hello", Kind: "const", Signature: "const (\n\tHello = \"hello\"\n\tHi = \"hi\"\n)"},
+		{Name: "Hi", Kind: "const", Signature: "const (\n\tHello = \"hello\"\n\tHi = \"hi\"\n)"},
+		{Name: "Loud", Kind: "var", Signature: "var Loud bool"},
+		{Name: "New", Kind: "func", Parent: "Greeter", Signature: "func New() *Greeter"},
+		{Name: "Shout", Kind: "func", Signature: "func Shout(s string) string"},
+		{Name: "Unknown", Kind: "func", Parent: "missing", Signature: "func Unknown() missing"},
+	}
+	page := &PkgPageData{Path: "example.com/greet", Name: "greet", ModPath: "example.com/greet", Version: "v1.0.0", Latest: "v1.0.0", Redistributable: true}
+	page.groupSymbols(symbols)
+
+	if len(page.Consts) != 1 || len(page.Consts[0].Names) != 2 || page.Consts[0].Anchor() != "Hello" {
+		t.Errorf("Consts = %+v", page.Consts)
+	}
+	if len(page.Vars) != 1 || page.Vars[0].Anchor() != "Loud" {
+		t.Errorf("Vars = %+v", page.Vars)
+	}
+	if len(page.Funcs) != 2 || page.Funcs[0].Anchor() != "Shout" || page.Funcs[1].Anchor() != "Unknown" {
+		t.Errorf("Funcs = %+v", page.Funcs)
+	}
+	if len(page.Types) != 1 {
+		t.Fatalf("Types = %+v", page.Types)
+	}
+	greeter := page.Types[0]
+	if greeter.Anchor() != "Greeter" || len(greeter.Funcs) != 1 || greeter.Funcs[0].Anchor() != "New" {
+		t.Errorf("Greeter = %+v", greeter)
+	}
+	if len(greeter.Methods) != 1 || greeter.Methods[0].Anchor() != "Greeter.Greet" {
+		t.Errorf("Greeter methods = %+v", greeter.Methods)
+	}
+
+	tmpl, err := template.New("pkg.html").ParseFiles("../../templates/pkg.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, page); err != nil {
+		t.Fatal(err)
+	}
+	// Each name of a grouped declaration is listed in the index and has an anchor
+	for _, name := range []string{"Hello", "Hi", "Loud"} {
+		if !strings.Contains(b.String(), `<li><a href="#`+name+`">`+name+`</a></li>`) || !strings.Contains(b.String(), `<span id="`+name+`"></span>`) {
+			t.Errorf("package page has no index entry or anchor for %s:\n%s", name, b.String())
+		}
+	}
+}
+
+// TestSymbolAnchors checks that every symbol the scanner indexes has an
+// anchor on its package page, since search results link to #Name.
+func TestSymbolAnchors(t *testing.T) {
+	dir := t.TempDir()
+	src := `package colors
+
+const (
+	Red, Green = "red", "green"
+	Blue       = "blue"
+)
+
+var Default, Fallback = Red, Blue
+
+var Palette []Color
+
+type Color string
+
+const (
+	Black Color = "black"
+	White Color = "white"
+)
+
+var Named, Unnamed []Color
+
+func Parse(s string) Color { return Color(s) }
+
+func (c Color) String() string { return string(c) }
+
+func Mix(a, b Color) Color { return a }
+`
+	for name, content := range map[string]string{"go.mod": "module example.com/colors\n", "colors.go": src} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pkgs, err := extract.Docs(dir, "example.com/colors")
+	if err != nil || len(pkgs) != 1 {
+		t.Fatalf("Docs = %v, %v", pkgs, err)
+	}
+	var symbols []*PkgSymbol
+	kinds := make(map[string]bool)
+	for _, sym := range pkgs[0].Symbols {
+		symbols = append(symbols, &PkgSymbol{Name: sym.Name, Kind: sym.Kind, Parent: sym.Parent, Signature: sym.Signature, Doc: sym.Doc})
+		kinds[sym.Kind] = true
+	}
+	if len(kinds) != 5 {
+		t.Fatalf("symbols of %d kinds, want all 5: %v", len(kinds), kinds)
+	}
+	page := &PkgPageData{Path: "example.com/colors", Name: "colors", ModPath: "example.com/colors", Version: "v1.0.0", Latest: "v1.0.0", Redistributable: true}
+	page.groupSymbols(symbols)
+
+	tmpl, err := template.New("pkg.html").ParseFiles("../../templates/pkg.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, page); err != nil {
+		t.Fatal(err)
+	}
+	for _, sym := range symbols {
+		if !strings.Contains(b.String(), `id="`+sym.Name+`"`) {
+			t.Errorf("package page has no anchor for %s %s", sym.Kind, sym.Name)
+		}
+	}
+}
@@ -1,7 +1,238 @@
 package main
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
 
-func TestSomething(t *testing.T) {
-	t.Skip("This test is not implemented yet")
+	"github.com/fflewddur/pantry/search"
+	"github.com/fflewddur/pantry/search/embedded"
+	"github.com/fflewddur/pantry/store"
+	"github.com/fflewddur/pantry/store/storetest"
+)
+
+// TestSearchHandler runs searches through the embedded search engine.
+func TestSearchHandler(t *testing.T) {
+	ctx := context.Background()
+	db := storetest.New(t)
+	searcher, err := embedded.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []*store.Module{
+		{Path: "example.com/router", Version: "v1.0.0", Readme: "A fast HTTP router."},
+		{Path: "example.com/yaml", Version: "v1.0.0", Readme: "YAML support."},
+	} {
+		m.Time = time.Now()
+		err := db.InTx(ctx, func(tx store.Tx) error {
+			err := tx.PutVersion(ctx, m)
+			if err != nil {
+				return err
+			}
+			return tx.PutModule(ctx, m)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var id uint64
+		err = db.QueryRow(ctx, "SELECT id FROM mods WHERE path = $1", m.Path).Scan(&id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = searcher.Index(ctx, search.Mods, &search.Document{ID: id, Fields: map[string]string{"path": m.Path, "readme": m.Readme}, Attrs: map[string]any{"license": "MIT"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir("../..") // For the templates
+	s := &Server{db: db, searcher: searcher}
+
+	rec := httptest.NewRecorder()
+	s.searchHandler(rec, httptest.NewRequest(http.MethodGet, "/search?q=http+router", nil))
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.Contains(body, `href="/mod/example.com/router"`) || strings.Contains(body, "example.com/yaml") {
+		t.Errorf("GET /search?q=http+router: status = %d, body = %s", rec.Code, body)
+	}
+	rec = httptest.NewRecorder()
+	s.searchHandler(rec, httptest.NewRequest(http.MethodGet, "/search?q=gopher", nil))
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "example.com/") {
+		t.Errorf("GET /search?q=gopher: status = %d, body = %s", rec.Code, rec.Body)
+	}
+}
+
+func TestPageParams(t *testing.T) {
+	tests := []struct {
+		query         string
+		page, perPage int
+	}{
+		{"", 1, defaultPerPage},
+		{"page=3", 3, defaultPerPage},
+		{"page=0&per_page=-5", 1, defaultPerPage},
+		{"page=x&per_page=y", 1, defaultPerPage},
+		{"page=2&per_page=25", 2, 25},
+		{"per_page=5000", 1, maxPerPage},
+		{"page=500&per_page=100", maxSearchResults / 100, 100},
+	}
+	for _, tt := range tests {
+		page, perPage := pageParams(httptest.NewRequest("GET", "/search?"+tt.query, nil))
+		if page != tt.page || perPage != tt.perPage {
+			t.Errorf("pageParams(%q) = %d, %d; want %d, %d", tt.query, page, perPage, tt.page, tt.perPage)
+		}
+	}
+}
+
+func TestSearchResultsPages(t *testing.T) {
+	sr := &SearchResults{Query: "http router", Page: 2, PerPage: 10, Start: 11, Total: 35, Licenses: []*Facet{{Value: "MIT", Count: 20}}}
+	sr.setPages()
+	if sr.PrevPage != 1 || sr.NextPage != 3 {
+		t.Errorf("page 2 of 35: prev = %d, next = %d", sr.PrevPage, sr.NextPage)
+	}
+	last := &SearchResults{Page: 4, PerPage: 10, Total: 35}
+	last.setPages()
+	if last.PrevPage != 3 || last.NextPage != 0 {
+		t.Errorf("page 4 of 35: prev = %d, next = %d", last.PrevPage, last.NextPage)
+	}
+
+	tmpl, err := template.New("results.html").ParseFiles("../../templates/results.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, sr); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), `href="/search?q=http%20router&page=3&per_page=10"`) {
+		t.Errorf("results page has no link to the next page:\n%s", b.String())
+	}
+	if !strings.Contains(b.String(), `href="/search?q=http%20router%20license%3aMIT"`) {
+		t.Errorf("results page has no license facet link:\n%s", b.String())
+	}
+}
+
+func TestModPageSQLite(t *testing.T) {
+	ctx := context.Background()
+	db := storetest.New(t)
+	for _, version := range []string{"v1.0.0", "v1.1.0"} {
+		m := &store.Module{Path: "example.com/hello", Version: version, Readme: "Hello " + version, Time: time.Now()}
+		err := db.InTx(ctx, func(tx store.Tx) error {
+			err := tx.PutVersion(ctx, m)
+			if err != nil {
+				return err
+			}
+			return tx.PutModule(ctx, m)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := &Server{db: db}
+	page, err := s.modPage(ctx, "example.com/hello", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Version != "v1.1.0" || page.Readme != "Hello v1.1.0" || len(page.Versions) != 2 {
+		t.Errorf("latest page = %+v", page)
+	}
+	page, err = s.modPage(ctx, "example.com/hello", "v1.0.0")
+	if err != nil || page.Readme != "Hello v1.0.0" {
+		t.Errorf("v1.0.0 page = %+v, %v", page, err)
+	}
+	_, err = s.modPage(ctx, "example.com/hello", "v2.0.0")
+	if !errors.Is(err, errNotFound) {
+		t.Errorf("unknown version: err = %v, want errNotFound", err)
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = s.modPage(cancelled, "example.com/hello", "")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled request: err = %v, want context.Canceled", err)
+	}
+}
+
+// TestImportersTab checks that a module whose path ends in /importers is
+// not mistaken for the importers of its parent, and that the /importers
+// suffix lists importers when no module has that path.
+func TestImportersTab(t *testing.T) {
+	ctx := context.Background()
+	db := storetest.New(t)
+	for _, path := range []string{"example.com/x", "example.com/x/importers"} {
+		m := &store.Module{Path: path, Version: "v1.0.0", Readme: "Readme of " + path, Time: time.Now()}
+		err := db.InTx(ctx, func(tx store.Tx) error {
+			err := tx.PutVersion(ctx, m)
+			if err != nil {
+				return err
+			}
+			return tx.PutModule(ctx, m)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, imported := range []string{"example.com/x", "example.com/z"} {
+		_, err := db.Exec(ctx, `INSERT INTO imports (mod_path, pkg_path, imported_path, imported_mod) VALUES ($1, $1, $2, $2)`, "example.com/y", imported)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir("../..") // For the templates
+	s := &Server{db: db}
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"/mod/example.com/x/importers", "Readme of example.com/x/importers"},
+		{"/mod/example.com/x?tab=importers", `<li><a href="/mod/example.com/y">example.com/y</a></li>`},
+		{"/mod/example.com/z/importers", `<li><a href="/mod/example.com/y">example.com/y</a></li>`},
+	}
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		s.modHandler(rec, httptest.NewRequest(http.MethodGet, test.url, nil))
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), test.want) {
+			t.Errorf("GET %s: status = %d, body = %s; want it to contain %s", test.url, rec.Code, rec.Body, test.want)
+		}
+	}
+}
+
+func TestModVersionsSQLite(t *testing.T) {
+	ctx := context.Background()
+	db := storetest.New(t)
+	// Stored out of order, as they would be when older branches get patch releases
+	for _, version := range []string{"v1.1.0", "v1.0.0", "v1.2.0-rc.1", "v1.0.1", "v1.1.1-0.20250701120000-0123456789ab", "v1.10.0"} {
+		err := db.InTx(ctx, func(tx store.Tx) error {
+			return tx.PutVersion(ctx, &store.Module{Path: "example.com/hello", Version: version, Time: time.Now()})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := &Server{db: db}
+	versions, err := s.modVersions(ctx, "example.com/hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, v := range versions {
+		got = append(got, v.Version)
+	}
+	want := "v1.10.0 v1.2.0-rc.1 v1.1.1-0.20250701120000-0123456789ab v1.1.0 v1.0.1 v1.0.0"
+	if strings.Join(got, " ") != want {
+		t.Errorf("modVersions = %v, want %s", got, want)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	s := &Server{timeout: time.Minute}
+	var deadline time.Time
+	h := s.withTimeout(func(w http.ResponseWriter, r *http.Request) {
+		deadline, _ = r.Context().Deadline()
+	})
+	h(httptest.NewRecorder(), httptest.NewRequest("GET", "/mod/example.com/hello", nil))
+	if until := time.Until(deadline); until <= 0 || until > time.Minute {
+		t.Errorf("request deadline is %v away, want at most a minute", until)
+	}
 }
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/fflewddur/pantry/search"
+)
+
+// symbolQuery reports whether q asks for a symbol search, written as "#Name"
+// or "symbol:Name", and returns the query with that prefix removed.
+func symbolQuery(q string) (string, bool) {
+	q = strings.TrimSpace(q)
+	if term, ok := strings.CutPrefix(q, "#"); ok {
+		return strings.TrimSpace(term), true
+	}
+	if term, ok := strings.CutPrefix(q, "symbol:"); ok {
+		return strings.TrimSpace(term), true
+	}
+	return "", false
+}
+
+// searchSymbols searches the symbols index for exported identifiers matching
+// term and adds them to searchResults.
+func (s *Server) searchSymbols(ctx context.Context, term string, searchResults *SearchResults) error {
+	res, err := s.searcher.Search(ctx, &search.Query{
+		Index:  search.Symbols,
+		Text:   term,
+		Offset: (searchResults.Page - 1) * searchResults.PerPage,
+		Limit:  searchResults.PerPage,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to execute symbol search: %w", err)
+	}
+	log.Printf("Symbol search hits: %d of %d", len(res.Hits), res.Total)
+	searchResults.Symbols = make([]*SymbolResult, 0, len(res.Hits))
+	for _, hit := range res.Hits {
+		sym, err := s.searchSymbol(ctx, hit.ID, hit.Score)
+		if err != nil {
+			return err
+		}
+		if sym != nil {
+			searchResults.Symbols = append(searchResults.Symbols, sym)
+		}
+	}
+	searchResults.Total = res.Total
+	searchResults.Took = int32(res.Took.Milliseconds())
+	if res.Warning != "" {
+		log.Printf("Symbol search warning: %v", res.Warning)
+		searchResults.Warnings = true
+	}
+	return nil
+}
+
+// searchSymbol loads the symbol with the given id for a search result, or
+// returns nil if it is not in the database. The synopsis is withheld when the
+// module declaring the symbol is not redistributable.
+func (s *Server) searchSymbol(ctx context.Context, id uint64, score float64) (*SymbolResult, error) {
+	sym := &SymbolResult{Score: score}
+	var redistributable sql.NullBool
+	err := s.db.QueryRow(ctx, `SELECT s.pkg_path, s.name, s.kind, s.signature, COALESCE(s.synopsis, ''), mv.redistributable FROM symbols s
+	LEFT JOIN pkgs p ON p.path = s.pkg_path AND p.version = s.version
+	LEFT JOIN modversions mv ON mv.path = p.mod_path AND mv.version = s.version
+	WHERE s.id = $1`, id).Scan(&sym.PkgPath, &sym.Name, &sym.Kind, &sym.Signature, &sym.Synopsis, &redistributable)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Symbol with ID %d not found in database", id)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query symbol ID %d: %w", id, err)
+	}
+	sym.Redistributable = isRedistributable(redistributable)
+	if !sym.Redistributable {
+		sym.Synopsis = ""
+	}
+	return sym, nil
+}
+
+type SymbolResult struct {
+	PkgPath   string  `json:"pkg_path"`
+	Name      string  `json:"name"` // Identifier; methods are named Type.Method
+	Kind      string  `json:"kind"`
+	Signature string  `json:"signature"`
+	Synopsis  string  `json:"synopsis"`
+	Score     float64 `json:"score"`
+
+	Redistributable bool `json:"redistributable"` // If false, Synopsis is withheld
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fflewddur/pantry/store"
+	"github.com/fflewddur/pantry/store/storetest"
+)
+
+func TestSymbolQuery(t *testing.T) {
+	tests := []struct {
+		q      string
+		term   string
+		symbol bool
+	}{
+		{"#Marshal", "Marshal", true},
+		{"symbol:NewClient", "NewClient", true},
+		{"  symbol: NewClient ", "NewClient", true},
+		{"json parser", "", false},
+		{"a #tag", "", false},
+	}
+	for _, tt := range tests {
+		term, symbol := symbolQuery(tt.q)
+		if term != tt.term || symbol != tt.symbol {
+			t.Errorf("symbolQuery(%q) = %q, %v; want %q, %v", tt.q, term, symbol, tt.term, tt.symbol)
+		}
+	}
+}
+
+func TestSearchSymbolPolicy(t *testing.T) {
+	ctx := context.Background()
+	db := storetest.New(t)
+	modules := []struct {
+		path            string
+		redistributable bool
+	}{
+		{"example.com/open", true},
+		{"example.com/closed", false},
+	}
+	for i, m := range modules {
+		err := db.InTx(ctx, func(tx store.Tx) error {
+			err := tx.PutVersion(ctx, &store.Module{Path: m.path, Version: "v1.0.0", Time: time.Now()})
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(ctx, `UPDATE modversions SET redistributable = $2 WHERE path = $1`, m.path, m.redistributable)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(ctx, `INSERT INTO pkgs (path, version, mod_path, name) VALUES ($1, 'v1.0.0', $1, 'p')`, m.path)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(ctx, `INSERT INTO symbols (id, pkg_path, version, name, kind, signature, synopsis) VALUES ($1, $2, 'v1.0.0', 'Open', 'func', 'func Open()', 'Open opens it.')`, i+1, m.path)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := &Server{db: db}
+	for i, m := range modules {
+		sym, err := s.searchSymbol(ctx, uint64(i+1), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sym.Redistributable != m.redistributable || (sym.Synopsis != "") != m.redistributable || sym.Signature != "func Open()" {
+			t.Errorf("symbol of %s = %+v", m.path, sym)
+		}
+	}
+	if sym, err := s.searchSymbol(ctx, 99, 1); sym != nil || err != nil {
+		t.Errorf("unknown symbol = %+v, %v", sym, err)
+	}
+}
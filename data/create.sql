@@ -1,7 +0,0 @@
-CREATE TABLE IF NOT EXISTS mods (
-    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
-    path TEXT NOT NULL UNIQUE,
-    version TEXT NOT NULL,
-    readme TEXT,
-    time DATETIME NOT NULL
-);
\ No newline at end of file
@@ -0,0 +1,187 @@
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Package is the documentation extracted from one package of a module.
+type Package struct {
+	Path     string // Import path
+	Name     string // Package name
+	Synopsis string // First sentence of the package comment
+	Doc      string // Full package comment
+	Symbols  []Symbol
+}
+
+// Symbol documents one exported identifier of a package.
+type Symbol struct {
+	Name      string // Identifier; methods are named Type.Method
+	Kind      string // const, var, func, type, or method
+	Parent    string // Type the symbol is grouped under, if any
+	Signature string // Declaration with function bodies removed
+	Synopsis  string // First sentence of Doc
+	Doc       string
+}
+
+// Docs parses every package in the unzipped module at dir with go/doc and
+// returns their documentation, ordered by import path. Packages that fail to
+// parse are logged and skipped.
+func Docs(dir, modPath string) ([]*Package, error) {
+	var pkgs []*Package
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if p != dir {
+			if skipDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			// Nested modules are scanned on their own
+			if _, err := os.Stat(filepath.Join(p, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		importPath := path.Join(modPath, filepath.ToSlash(rel))
+		pkg, err := extractPackage(p, importPath)
+		if err != nil {
+			log.Printf("Failed to extract docs for package %s: %v", importPath, err)
+			return nil
+		}
+		if pkg != nil {
+			pkgs = append(pkgs, pkg)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk module directory %s: %w", dir, err)
+	}
+	return pkgs, nil
+}
+
+// extractPackage documents the package in dir, or returns nil if dir holds no Go files.
+func extractPackage(dir, importPath string) (*Package, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	byName := make(map[string][]*ast.File) // Files grouped by package clause
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			log.Printf("Skipping %s: %v", filepath.Join(dir, name), err)
+			continue
+		}
+		byName[f.Name.Name] = append(byName[f.Name.Name], f)
+	}
+	if len(byName) == 0 {
+		return nil, nil
+	}
+
+	// A directory can mix in files for other packages (usually guarded by an
+	// ignore build tag), so document the package with the most files.
+	var files []*ast.File
+	for name, pkgFiles := range byName {
+		if len(pkgFiles) > len(files) || (len(pkgFiles) == len(files) && name == path.Base(importPath)) {
+			files = pkgFiles
+		}
+	}
+	p, err := doc.NewFromFiles(fset, files, importPath)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg := &Package{
+		Path:     importPath,
+		Name:     p.Name,
+		Synopsis: p.Synopsis(p.Doc),
+		Doc:      p.Doc,
+	}
+	addValues := func(values []*doc.Value, kind, parent string) {
+		for _, v := range values {
+			sig := printNode(fset, &ast.GenDecl{Tok: v.Decl.Tok, Lparen: v.Decl.Lparen, Specs: v.Decl.Specs, Rparen: v.Decl.Rparen})
+			for _, name := range v.Names {
+				pkg.Symbols = append(pkg.Symbols, Symbol{Name: name, Kind: kind, Parent: parent, Signature: sig, Synopsis: p.Synopsis(v.Doc), Doc: v.Doc})
+			}
+		}
+	}
+	addFuncs := func(funcs []*doc.Func, kind, parent string) {
+		for _, f := range funcs {
+			name := f.Name
+			if kind == "method" {
+				name = parent + "." + f.Name
+			}
+			sig := printNode(fset, &ast.FuncDecl{Recv: f.Decl.Recv, Name: f.Decl.Name, Type: f.Decl.Type})
+			pkg.Symbols = append(pkg.Symbols, Symbol{Name: name, Kind: kind, Parent: parent, Signature: sig, Synopsis: p.Synopsis(f.Doc), Doc: f.Doc})
+		}
+	}
+	addValues(p.Consts, "const", "")
+	addValues(p.Vars, "var", "")
+	addFuncs(p.Funcs, "func", "")
+	for _, t := range p.Types {
+		spec := *t.Decl.Specs[0].(*ast.TypeSpec)
+		spec.Doc, spec.Comment = nil, nil
+		sig := printNode(fset, &ast.GenDecl{Tok: token.TYPE, Specs: []ast.Spec{&spec}})
+		pkg.Symbols = append(pkg.Symbols, Symbol{Name: t.Name, Kind: "type", Signature: sig, Synopsis: p.Synopsis(t.Doc), Doc: t.Doc})
+		addValues(t.Consts, "const", t.Name)
+		addValues(t.Vars, "var", t.Name)
+		addFuncs(t.Funcs, "func", t.Name)
+		addFuncs(t.Methods, "method", t.Name)
+	}
+	return pkg, nil
+}
+
+// printNode formats an AST node the way gofmt would.
+func printNode(fset *token.FileSet, node any) string {
+	var buf bytes.Buffer
+	err := (&printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}).Fprint(&buf, fset, node)
+	if err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// Text renders extracted documentation as plain text, roughly in the style of
+// 'go doc -all', so that it can be indexed for full-text search.
+func Text(pkgs []*Package) string {
+	var b strings.Builder
+	for _, p := range pkgs {
+		fmt.Fprintf(&b, "package %s // import %q\n\n", p.Name, p.Path)
+		if p.Doc != "" {
+			b.WriteString(p.Doc)
+			b.WriteByte('\n')
+		}
+		for _, s := range p.Symbols {
+			b.WriteString(s.Signature)
+			b.WriteByte('\n')
+			if s.Doc != "" {
+				b.WriteString(s.Doc)
+			}
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
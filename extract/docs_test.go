@@ -0,0 +1,92 @@
+package extract
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExtractDocs(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/greet\n",
+		"greet.go": `// Package greet builds greetings. It is very polite.
+package greet
+
+// Default greetings.
+const (
+	Hello = "hello"
+	Hi    = "hi"
+	quiet = "..."
+)
+
+// Greeter greets people.
+type Greeter struct {
+	Name    string
+	counter int
+}
+
+// New returns a Greeter for name.
+func New(name string) *Greeter { return &Greeter{Name: name} }
+
+// Greet returns the greeting.
+func (g *Greeter) Greet() string { g.counter++; return Hello + " " + g.Name }
+
+func helper() {}
+`,
+		"gen.go":            "//go:build ignore\n\npackage main\n\nfunc main() {}\n",
+		"loud/loud.go":      "// Package loud shouts.\npackage loud\n\n// Shout shouts.\nfunc Shout(s string) string { return s }\n",
+		"loud/loud_test.go": "package loud\n\nfunc TestShout() {}\n",
+		"testdata/t.go":     "package testdata\n",
+		"empty/README.md":   "no Go here",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pkgs, err := Docs(dir, "example.com/greet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkgs) != 2 || pkgs[0].Path != "example.com/greet" || pkgs[1].Path != "example.com/greet/loud" {
+		t.Fatalf("unexpected packages: %+v", pkgs)
+	}
+	greet := pkgs[0]
+	if greet.Name != "greet" || greet.Synopsis != "Package greet builds greetings." {
+		t.Errorf("name = %q, synopsis = %q", greet.Name, greet.Synopsis)
+	}
+
+	symbols := make(map[string]Symbol)
+	for _, s := range greet.Symbols {
+		symbols[s.Name] = s
+	}
+	for _, name := range []string{"quiet", "helper", "main", "Greeter.counter"} {
+		if _, ok := symbols[name]; ok {
+			t.Errorf("unexported or ignored symbol %s was documented", name)
+		}
+	}
+	if s := symbols["Hi"]; s.Kind != "const" || !strings.Contains(s.Signature, `Hello = "hello"`) || s.Doc != "Default greetings.\n" {
+		t.Errorf("Hi = %+v", s)
+	}
+	if s := symbols["New"]; s.Kind != "func" || s.Parent != "Greeter" || s.Synopsis != "New returns a Greeter for name." || s.Signature != "func New(name string) *Greeter" {
+		t.Errorf("New = %+v", s)
+	}
+	if s := symbols["Greeter.Greet"]; s.Kind != "method" || s.Signature != "func (g *Greeter) Greet() string" {
+		t.Errorf("Greeter.Greet = %+v", s)
+	}
+	if s := symbols["Greeter"]; s.Kind != "type" || strings.Contains(s.Signature, "counter int") {
+		t.Errorf("Greeter = %+v", s)
+	}
+
+	text := Text(pkgs)
+	if !strings.Contains(text, `package loud // import "example.com/greet/loud"`) || !strings.Contains(text, "Shout shouts.") {
+		t.Errorf("Text is missing the loud package:\n%s", text)
+	}
+}
@@ -0,0 +1,179 @@
+// Package extract pulls the contents pantry indexes out of a module zip: its
+// README, go.mod, package documentation, imports, and licenses, along with the
+// license policy's verdict on them.
+//
+// It is the scanner's extraction pipeline, and can be used on its own, for
+// example to check modules in CI before they are published:
+//
+//	e := &extract.Extractor{}
+//	res, err := e.Extract("example.com/mod", "v1.0.0", time.Now(), zipData)
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-enry/go-license-detector/v4/licensedb"
+	"github.com/go-enry/go-license-detector/v4/licensedb/api"
+	"github.com/go-enry/go-license-detector/v4/licensedb/filer"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	modzip "golang.org/x/mod/zip"
+)
+
+// LicenseConfidenceThreshold is the minimum confidence for a detected license
+// to be considered.
+const LicenseConfidenceThreshold = 0.9
+
+// Extractor extracts the contents of module zips.
+type Extractor struct {
+	ScratchDir string  // Directory that modules are unzipped into; os.TempDir() if empty
+	Policy     *Policy // License policy; nil uses DefaultAllowedLicenses and denies unknown licenses
+}
+
+// Result is everything extracted from one version of a module.
+type Result struct {
+	Path    string
+	Version string
+	Time    time.Time
+	Readme  string        // Contents of every README file
+	Docs    string        // Plain-text documentation of every package, see Text
+	GoMod   *modfile.File // Parsed go.mod, or nil if the module has none
+
+	Imports  []Import   // Packages imported by the module's packages
+	Packages []*Package // Documentation for each package in the module
+
+	Licenses       []string       // SPDX IDs detected above LicenseConfidenceThreshold
+	PrimaryLicense string         // The license with the highest confidence
+	LicenseMatches []LicenseMatch // Every detection, including low-confidence ones
+
+	PolicyStatus    string // One of PolicyAllowed, PolicyDenied, or PolicyUnknown
+	Redistributable bool   // Whether the README and docs may be shown
+}
+
+var readmeRegex = regexp.MustCompile(`(?i)readme(\.md|\.txt)?$`)
+
+// Extract unzips data, the zip of path@version as served by a module proxy,
+// and extracts its contents. Parts of a module that cannot be parsed, such as
+// a broken go.mod, are logged and left out rather than failing the module.
+func (e *Extractor) Extract(path, version string, t time.Time, data []byte) (res *Result, err error) {
+	res = &Result{Path: path, Version: version, Time: t}
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zip reader: %w", err)
+	}
+
+	tmpDir, err := os.MkdirTemp(e.ScratchDir, "mod-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer func() {
+		err = errors.Join(err, os.RemoveAll(tmpDir)) // Clean up the temporary directory after processing
+	}()
+
+	// Save the module bytes to a temporary file
+	zipPath := filepath.Join(tmpDir, "module.zip")
+	err = os.WriteFile(zipPath, data, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write module zip file %s: %w", zipPath, err)
+	}
+	// Unzip the module contents to the temporary directory
+	mv := module.Version{
+		Path:    path,
+		Version: version,
+	}
+	log.Printf("Unzipping module %s version %s to %s", path, version, tmpDir)
+	modDir := filepath.Join(tmpDir, "unzipped")
+	err = modzip.Unzip(modDir, mv, zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unzip module %s: %w", path, err)
+	}
+
+	res.Readme = readmes(reader)
+	res.GoMod, err = GoMod(modDir)
+	if err != nil {
+		log.Printf("Failed to parse go.mod for module %s: %v", path, err)
+	}
+	res.Imports, err = Imports(modDir, path, res.GoMod)
+	if err != nil {
+		log.Printf("Failed to parse imports for module %s: %v", path, err)
+	}
+	res.Packages, err = Docs(modDir, path)
+	if err != nil {
+		log.Printf("Failed to extract docs for module %s: %v", path, err)
+	}
+	res.Docs = Text(res.Packages)
+
+	f, err := filer.FromDirectory(modDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create filer from directory %s: %w", modDir, err)
+	}
+	licenses, err := licensedb.Detect(f)
+	if err != nil {
+		if !errors.Is(err, licensedb.ErrNoLicenseFound) {
+			return nil, fmt.Errorf("failed to detect licenses: %w", err)
+		}
+		log.Printf("No license found in module %s", path)
+	}
+	res.Licenses = filterLicenses(licenses)
+	res.LicenseMatches = licenseMatches(licenses)
+	res.PrimaryLicense = PrimaryLicense(res.LicenseMatches)
+
+	policy := e.Policy
+	if policy == nil {
+		policy = NewPolicy(DefaultAllowedLicenses, nil, false)
+	}
+	res.PolicyStatus, res.Redistributable = policy.Evaluate(res.Licenses)
+	return res, nil
+}
+
+// readmes returns the contents of every README file in a module zip, one
+// after the other. Files that are not valid UTF-8 are skipped.
+func readmes(reader *zip.Reader) string {
+	var b strings.Builder
+	for _, file := range reader.File {
+		if !readmeRegex.MatchString(file.Name) {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			log.Printf("Failed to open file %s in zip: %v", file.Name, err)
+			continue
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			log.Printf("Failed to read content of file %s: %v", file.Name, err)
+			continue
+		}
+		if !utf8.Valid(content) {
+			log.Printf("File %s is not valid UTF-8, skipping", file.Name)
+			continue
+		}
+		b.Write(content)
+		b.WriteByte('\n') // Add a newline for separation
+	}
+	return b.String()
+}
+
+// filterLicenses returns the IDs of the licenses detected with at least
+// LicenseConfidenceThreshold.
+func filterLicenses(licenses map[string]api.Match) []string {
+	var filtered []string
+	for k, m := range licenses {
+		if m.Confidence >= LicenseConfidenceThreshold {
+			filtered = append(filtered, k)
+		}
+	}
+	return filtered
+}
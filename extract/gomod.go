@@ -0,0 +1,37 @@
+package extract
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/mod/modfile"
+)
+
+// GoMod reads the go.mod file in the root of the unzipped module at dir.
+// Modules published before go.mod existed have no file, in which case it
+// returns nil.
+func GoMod(dir string) (*modfile.File, error) {
+	path := filepath.Join(dir, "go.mod")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	f, err := modfile.Parse(path, data, nil)
+	if err != nil {
+		// Newer go.mod syntax may not be understood by our copy of x/mod, so
+		// fall back to the lax parser, which skips unknown directives along
+		// with replace and exclude blocks.
+		log.Printf("Failed to parse %s strictly, retrying in lax mode: %v", path, err)
+		f, err = modfile.ParseLax(path, data, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+	return f, nil
+}
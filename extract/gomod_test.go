@@ -0,0 +1,54 @@
+package extract
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseGoMod(t *testing.T) {
+	dir := t.TempDir()
+	goMod := `module example.com/foo
+
+go 1.23.0
+
+toolchain go1.24.2
+
+require (
+	example.com/direct v1.2.3
+	example.com/indirect v0.1.0 // indirect
+)
+
+replace example.com/direct v1.2.3 => ../direct
+
+exclude example.com/bad v1.0.0
+
+retract v1.0.1 // Published accidentally.
+`
+	err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := GoMod(dir)
+	if err != nil {
+		t.Fatalf("GoMod: %v", err)
+	}
+	if f.Go.Version != "1.23.0" || f.Toolchain.Name != "go1.24.2" {
+		t.Errorf("go = %s, toolchain = %s", f.Go.Version, f.Toolchain.Name)
+	}
+	if len(f.Require) != 2 || f.Require[0].Indirect || !f.Require[1].Indirect {
+		t.Errorf("unexpected requirements: %+v", f.Require)
+	}
+	if len(f.Replace) != 1 || f.Replace[0].New.Path != "../direct" {
+		t.Errorf("unexpected replacements: %+v", f.Replace)
+	}
+	if len(f.Exclude) != 1 || len(f.Retract) != 1 || f.Retract[0].Rationale != "Published accidentally." {
+		t.Errorf("unexpected exclusions %+v or retractions %+v", f.Exclude, f.Retract)
+	}
+
+	// Modules without a go.mod file are not an error
+	f, err = GoMod(t.TempDir())
+	if err != nil || f != nil {
+		t.Errorf("GoMod on empty dir = %v, %v; want nil, nil", f, err)
+	}
+}
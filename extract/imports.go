@@ -0,0 +1,119 @@
+package extract
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// Import records that a package in a module imports another package.
+type Import struct {
+	PkgPath      string // Importing package
+	ImportedPath string // Imported package
+	ImportedMod  string // Module providing the imported package, or "" if unknown
+}
+
+// Imports walks the unzipped module at dir and returns the non-test imports
+// of each of its packages. Standard library imports are omitted. Imported
+// packages are attributed to the longest matching module path among the
+// module itself and its go.mod requirements.
+func Imports(dir, modPath string, goMod *modfile.File) ([]Import, error) {
+	mods := []string{modPath}
+	if goMod != nil {
+		for _, r := range goMod.Require {
+			mods = append(mods, r.Mod.Path)
+		}
+	}
+
+	seen := make(map[Import]bool)
+	fset := token.NewFileSet()
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p == dir {
+				return nil
+			}
+			if skipDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			// Nested modules are scanned on their own
+			if _, err := os.Stat(filepath.Join(p, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(d.Name(), ".go") || strings.HasSuffix(d.Name(), "_test.go") {
+			return nil
+		}
+		f, err := parser.ParseFile(fset, p, nil, parser.ImportsOnly)
+		if err != nil {
+			return nil // Skip files that don't parse; the Go tool would reject them too
+		}
+		pkgPath := path.Join(modPath, filepath.ToSlash(filepath.Dir(rel)))
+		for _, spec := range f.Imports {
+			imported, err := strconv.Unquote(spec.Path.Value)
+			if err != nil || isStdImport(imported) {
+				continue
+			}
+			seen[Import{
+				PkgPath:      pkgPath,
+				ImportedPath: imported,
+				ImportedMod:  owningModule(imported, mods),
+			}] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk module directory %s: %w", dir, err)
+	}
+
+	edges := make([]Import, 0, len(seen))
+	for e := range seen {
+		edges = append(edges, e)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].PkgPath != edges[j].PkgPath {
+			return edges[i].PkgPath < edges[j].PkgPath
+		}
+		return edges[i].ImportedPath < edges[j].ImportedPath
+	})
+	return edges, nil
+}
+
+// skipDir reports whether the Go tool ignores packages in directories with this name.
+func skipDir(name string) bool {
+	return name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
+
+// isStdImport reports whether an import path belongs to the standard library
+// (or is the cgo pseudo-package), which is the case when its first element has no dot.
+func isStdImport(importPath string) bool {
+	first, _, _ := strings.Cut(importPath, "/")
+	return !strings.Contains(first, ".")
+}
+
+// owningModule returns the longest module path in mods that contains importPath.
+func owningModule(importPath string, mods []string) string {
+	owner := ""
+	for _, m := range mods {
+		if (importPath == m || strings.HasPrefix(importPath, m+"/")) && len(m) > len(owner) {
+			owner = m
+		}
+	}
+	return owner
+}
@@ -0,0 +1,48 @@
+package extract
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/mod/modfile"
+)
+
+func TestParseImports(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"foo.go":             "package foo\n\nimport (\n\t\"fmt\"\n\t\"example.com/dep/sub\"\n\t\"example.com/foo/internal/util\"\n)\n",
+		"foo_test.go":        "package foo\n\nimport \"example.com/testonly\"\n",
+		"internal/util/u.go": "package util\n\nimport \"example.com/dep/v2\"\n",
+		"testdata/x.go":      "package x\n\nimport \"example.com/ignored\"\n",
+		"nested/go.mod":      "module example.com/foo/nested\n",
+		"nested/n.go":        "package nested\n\nimport \"example.com/ignored\"\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	goMod, err := modfile.Parse("go.mod", []byte("module example.com/foo\n\nrequire (\n\texample.com/dep v1.0.0\n\texample.com/dep/v2 v2.0.0\n)\n"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edges, err := Imports(dir, "example.com/foo", goMod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Import{
+		{PkgPath: "example.com/foo", ImportedPath: "example.com/dep/sub", ImportedMod: "example.com/dep"},
+		{PkgPath: "example.com/foo", ImportedPath: "example.com/foo/internal/util", ImportedMod: "example.com/foo"},
+		{PkgPath: "example.com/foo/internal/util", ImportedPath: "example.com/dep/v2", ImportedMod: "example.com/dep/v2"},
+	}
+	if !reflect.DeepEqual(edges, want) {
+		t.Errorf("Imports =\n%+v\nwant\n%+v", edges, want)
+	}
+}
@@ -0,0 +1,53 @@
+package extract
+
+import (
+	"sort"
+
+	"github.com/go-enry/go-license-detector/v4/licensedb/api"
+)
+
+// LicenseMatch is one file's evidence for a detected license.
+type LicenseMatch struct {
+	License    string  // SPDX ID
+	File       string  // Path of the matching file, relative to the module root
+	Confidence float32 // Between 0 and 1
+}
+
+// licenseMatches flattens the results of licensedb.Detect into one match per
+// license and file, ordered by license and then by decreasing confidence.
+func licenseMatches(licenses map[string]api.Match) []LicenseMatch {
+	var matches []LicenseMatch
+	for id, m := range licenses {
+		if len(m.Files) == 0 {
+			matches = append(matches, LicenseMatch{License: id, File: m.File, Confidence: m.Confidence})
+			continue
+		}
+		for file, confidence := range m.Files {
+			matches = append(matches, LicenseMatch{License: id, File: file, Confidence: confidence})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].License != matches[j].License {
+			return matches[i].License < matches[j].License
+		}
+		if matches[i].Confidence != matches[j].Confidence {
+			return matches[i].Confidence > matches[j].Confidence
+		}
+		return matches[i].File < matches[j].File
+	})
+	return matches
+}
+
+// PrimaryLicense returns the license of the most confident match above
+// LicenseConfidenceThreshold, or "" if there is none.
+func PrimaryLicense(matches []LicenseMatch) string {
+	var prime string
+	var maxConfidence float32
+	for _, m := range matches {
+		if m.Confidence > LicenseConfidenceThreshold && m.Confidence > maxConfidence {
+			maxConfidence = m.Confidence
+			prime = m.License
+		}
+	}
+	return prime
+}
@@ -0,0 +1,41 @@
+package extract
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-enry/go-license-detector/v4/licensedb/api"
+)
+
+func TestLicenseMatches(t *testing.T) {
+	licenses := map[string]api.Match{
+		"MIT":        {Confidence: 0.98, File: "LICENSE", Files: map[string]float32{"LICENSE": 0.98, "vendor/x/LICENSE.txt": 0.95}},
+		"Apache-2.0": {Confidence: 0.91, File: "third_party/NOTICE"},
+	}
+	want := []LicenseMatch{
+		{License: "Apache-2.0", File: "third_party/NOTICE", Confidence: 0.91},
+		{License: "MIT", File: "LICENSE", Confidence: 0.98},
+		{License: "MIT", File: "vendor/x/LICENSE.txt", Confidence: 0.95},
+	}
+	if got := licenseMatches(licenses); !reflect.DeepEqual(got, want) {
+		t.Errorf("licenseMatches = %+v, want %+v", got, want)
+	}
+	if got := licenseMatches(nil); got != nil {
+		t.Errorf("licenseMatches(nil) = %+v, want nil", got)
+	}
+}
+
+func TestPrimaryLicense(t *testing.T) {
+	matches := []LicenseMatch{
+		{License: "Apache-2.0", File: "NOTICE", Confidence: 0.93},
+		{License: "BSD-3-Clause", File: "LICENSE", Confidence: 0.5},
+		{License: "MIT", File: "LICENSE", Confidence: 0.97},
+		{License: "MIT", File: "third_party/LICENSE", Confidence: 0.91},
+	}
+	if got := PrimaryLicense(matches); got != "MIT" {
+		t.Errorf("PrimaryLicense = %q, want MIT", got)
+	}
+	if got := PrimaryLicense(matches[1:2]); got != "" {
+		t.Errorf("PrimaryLicense of low-confidence match = %q, want none", got)
+	}
+}
@@ -0,0 +1,70 @@
+package extract
+
+import (
+	"strings"
+)
+
+// License policy statuses stored in modversions and modsmeta.
+const (
+	PolicyAllowed = "allowed" // Every detected license is on the allow list
+	PolicyDenied  = "denied"  // At least one detected license is on the deny list
+	PolicyUnknown = "unknown" // No license was detected with enough confidence, or one is on neither list
+)
+
+// DefaultAllowedLicenses are permissive licenses that let us show a module's
+// README and documentation.
+var DefaultAllowedLicenses = []string{
+	"0BSD", "Apache-2.0", "BSD-2-Clause", "BSD-3-Clause", "BSL-1.0", "CC0-1.0",
+	"ISC", "MIT", "MIT-0", "MPL-2.0", "Unlicense", "Zlib",
+}
+
+// Policy decides whether a module's licenses allow us to redistribute its
+// contents.
+type Policy struct {
+	allow        map[string]bool
+	deny         map[string]bool
+	allowUnknown bool // Whether modules with an unknown status are redistributable
+}
+
+// NewPolicy returns a policy allowing and denying the given SPDX IDs. Blank
+// IDs are ignored. allowUnknown decides whether modules without a confident
+// license detection, or with a license on neither list, are redistributable.
+func NewPolicy(allow, deny []string, allowUnknown bool) *Policy {
+	return &Policy{
+		allow:        licenseSet(allow),
+		deny:         licenseSet(deny),
+		allowUnknown: allowUnknown,
+	}
+}
+
+func licenseSet(ids []string) map[string]bool {
+	set := make(map[string]bool)
+	for _, id := range ids {
+		if id = strings.TrimSpace(id); id != "" {
+			set[id] = true
+		}
+	}
+	return set
+}
+
+// Evaluate returns the policy status of a module with the given licenses,
+// which should only include detections above LicenseConfidenceThreshold, and
+// whether its README and documentation may be shown.
+func (p *Policy) Evaluate(licenses []string) (status string, redistributable bool) {
+	if len(licenses) == 0 {
+		return PolicyUnknown, p.allowUnknown
+	}
+	status = PolicyAllowed
+	for _, id := range licenses {
+		if p.deny[id] {
+			return PolicyDenied, false
+		}
+		if !p.allow[id] {
+			status = PolicyUnknown
+		}
+	}
+	if status == PolicyUnknown {
+		return status, p.allowUnknown
+	}
+	return status, true
+}
@@ -0,0 +1,32 @@
+package extract
+
+import "testing"
+
+func TestPolicy(t *testing.T) {
+	p := NewPolicy([]string{"MIT", " Apache-2.0 ", ""}, []string{"AGPL-3.0"}, false)
+	tests := []struct {
+		licenses        []string
+		status          string
+		redistributable bool
+	}{
+		{nil, PolicyUnknown, false},
+		{[]string{"MIT"}, PolicyAllowed, true},
+		{[]string{"MIT", "Apache-2.0"}, PolicyAllowed, true},
+		{[]string{"MIT", "AGPL-3.0"}, PolicyDenied, false},
+		{[]string{"MIT", "GPL-2.0"}, PolicyUnknown, false},
+	}
+	for _, tt := range tests {
+		status, redistributable := p.Evaluate(tt.licenses)
+		if status != tt.status || redistributable != tt.redistributable {
+			t.Errorf("Evaluate(%q) = %s, %v; want %s, %v", tt.licenses, status, redistributable, tt.status, tt.redistributable)
+		}
+	}
+
+	p.allowUnknown = true
+	if status, redistributable := p.Evaluate([]string{"GPL-2.0"}); status != PolicyUnknown || !redistributable {
+		t.Errorf("Evaluate with unknown licenses allowed = %s, %v", status, redistributable)
+	}
+	if _, redistributable := p.Evaluate([]string{"AGPL-3.0"}); redistributable {
+		t.Error("denied license is redistributable when unknown licenses are allowed")
+	}
+}
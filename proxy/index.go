@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ModuleEntry represents a single module entry in the Go index.
+type ModuleEntry struct {
+	Path      string    `json:"Path"`
+	Version   string    `json:"Version"`
+	Timestamp time.Time `json:"Timestamp"`
+}
+
+// Index reads the module index feed served by index.golang.org (or a compatible server).
+type Index struct {
+	url    string
+	client *Client
+}
+
+// NewIndex returns a client for the index feed at indexURL.
+func NewIndex(indexURL string, httpClient *http.Client) *Index {
+	if indexURL == "" {
+		indexURL = DefaultIndex
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Index{
+		url:    indexURL,
+		client: &Client{httpClient: httpClient},
+	}
+}
+
+// Entries returns up to limit index entries with timestamps at or after since, oldest first.
+func (x *Index) Entries(ctx context.Context, since time.Time, limit int) ([]ModuleEntry, error) {
+	q := url.Values{}
+	q.Set("since", since.UTC().Format(time.RFC3339Nano))
+	q.Set("limit", strconv.Itoa(limit))
+	u := x.url + "?" + q.Encode()
+	log.Printf("Requesting URL: %s", u)
+	data, err := x.client.get(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	var entries []ModuleEntry
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(line) == 0 {
+			continue // Skip empty lines
+		}
+		var entry ModuleEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			log.Printf("Error unmarshaling index entry %s: %v", line, err)
+			continue // Skip errors when unmarshaling
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
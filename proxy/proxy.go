@@ -0,0 +1,299 @@
+// Package proxy implements clients for the Go module proxy protocol and for
+// the module index feed served by index.golang.org.
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// DefaultProxy is the proxy list used when none is configured.
+const DefaultProxy = "https://proxy.golang.org/cached-only"
+
+// DefaultIndex is the index feed used when none is configured.
+const DefaultIndex = "https://index.golang.org/index"
+
+// ErrNotFound is returned when no proxy in the list has the requested module or version.
+var ErrNotFound = errors.New("not found")
+
+// Info is the metadata returned by a proxy's .info and @latest endpoints.
+type Info struct {
+	Version string    // version string
+	Time    time.Time // commit time
+}
+
+// Client fetches module metadata and contents from a GOPROXY-style list of proxies.
+type Client struct {
+	httpClient *http.Client
+	proxies    []proxyEntry
+
+	mu          sync.Mutex
+	maxPerHost  int
+	hostLimiter map[string]chan struct{} // host -> semaphore bounding concurrent requests
+}
+
+type proxyEntry struct {
+	url         string
+	fallBackAny bool // true if the entry was followed by '|', so any error falls back to the next proxy
+}
+
+// NewClient returns a client for the given proxy list. The list uses the
+// same syntax as GOPROXY: entries separated by ',' fall back to the next proxy
+// only on a 404 or 410 response, while entries separated by '|' fall back on
+// any error. Entries may be http(s):// URLs, file:// URLs laid out per the
+// GOPROXY protocol, "off", or "direct" (which is not supported and always fails).
+func NewClient(proxyList string, httpClient *http.Client) (*Client, error) {
+	if proxyList == "" {
+		proxyList = DefaultProxy
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	c := &Client{httpClient: httpClient}
+	for proxyList != "" {
+		var entry proxyEntry
+		i := strings.IndexAny(proxyList, ",|")
+		if i < 0 {
+			entry.url, proxyList = proxyList, ""
+		} else {
+			entry.url, entry.fallBackAny, proxyList = proxyList[:i], proxyList[i] == '|', proxyList[i+1:]
+		}
+		entry.url = strings.TrimSpace(entry.url)
+		if entry.url == "" {
+			continue
+		}
+		switch entry.url {
+		case "off", "direct":
+		default:
+			u, err := url.Parse(entry.url)
+			if err != nil {
+				return nil, fmt.Errorf("invalid proxy URL %q: %w", entry.url, err)
+			}
+			if u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "file" {
+				return nil, fmt.Errorf("unsupported proxy URL scheme %q in %q", u.Scheme, entry.url)
+			}
+			entry.url = strings.TrimSuffix(entry.url, "/")
+		}
+		c.proxies = append(c.proxies, entry)
+	}
+	if len(c.proxies) == 0 {
+		return nil, errors.New("empty proxy list")
+	}
+	return c, nil
+}
+
+// SetMaxConnsPerHost bounds the number of concurrent requests the client makes
+// to any single proxy host. A value of zero or less removes the limit.
+func (c *Client) SetMaxConnsPerHost(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxPerHost = n
+	c.hostLimiter = make(map[string]chan struct{})
+}
+
+func (c *Client) limiter(host string) chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.maxPerHost <= 0 {
+		return nil
+	}
+	sem, ok := c.hostLimiter[host]
+	if !ok {
+		sem = make(chan struct{}, c.maxPerHost)
+		c.hostLimiter[host] = sem
+	}
+	return sem
+}
+
+// Latest returns the latest version of the module at path. If a proxy does
+// not serve @latest, the highest version from its @v/list is used instead.
+func (c *Client) Latest(ctx context.Context, path string) (*Info, error) {
+	escPath, err := module.EscapePath(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid module path %s: %w", path, err)
+	}
+	info, err := c.info(ctx, escPath+"/@latest")
+	if err == nil || !errors.Is(err, ErrNotFound) {
+		return info, err
+	}
+	versions, err := c.List(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	// Prefer the highest release, falling back to the highest prerelease.
+	latest := ""
+	for _, v := range versions {
+		if latest == "" || better(v, latest) {
+			latest = v
+		}
+	}
+	if latest == "" {
+		return nil, fmt.Errorf("no versions of %s: %w", path, ErrNotFound)
+	}
+	return c.Info(ctx, path, latest)
+}
+
+// Info returns the metadata for the given module version.
+func (c *Client) Info(ctx context.Context, path, version string) (*Info, error) {
+	rel, err := versionPath(path, version, ".info")
+	if err != nil {
+		return nil, err
+	}
+	return c.info(ctx, rel)
+}
+
+// List returns the versions of the module at path known to the proxy.
+func (c *Client) List(ctx context.Context, path string) ([]string, error) {
+	escPath, err := module.EscapePath(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid module path %s: %w", path, err)
+	}
+	data, err := c.fetch(ctx, escPath+"/@v/list")
+	if err != nil {
+		return nil, err
+	}
+	var versions []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if v, _, _ := strings.Cut(strings.TrimSpace(line), " "); v != "" {
+			versions = append(versions, v)
+		}
+	}
+	return versions, nil
+}
+
+// Mod returns the go.mod file for the given module version.
+func (c *Client) Mod(ctx context.Context, path, version string) ([]byte, error) {
+	rel, err := versionPath(path, version, ".mod")
+	if err != nil {
+		return nil, err
+	}
+	return c.fetch(ctx, rel)
+}
+
+// Zip returns the zip archive for the given module version.
+func (c *Client) Zip(ctx context.Context, path, version string) ([]byte, error) {
+	rel, err := versionPath(path, version, ".zip")
+	if err != nil {
+		return nil, err
+	}
+	return c.fetch(ctx, rel)
+}
+
+func (c *Client) info(ctx context.Context, rel string) (*Info, error) {
+	data, err := c.fetch(ctx, rel)
+	if err != nil {
+		return nil, err
+	}
+	var info Info
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("error unmarshaling %s: %w", rel, err)
+	}
+	return &info, nil
+}
+
+// fetch walks the proxy list and returns the first successful response for rel,
+// which is a path relative to the proxy root such as "golang.org/x/mod/@v/list".
+func (c *Client) fetch(ctx context.Context, rel string) ([]byte, error) {
+	var errs error
+	for _, p := range c.proxies {
+		data, err := c.fetchFrom(ctx, p.url, rel)
+		if err == nil {
+			return data, nil
+		}
+		errs = errors.Join(errs, err)
+		if !p.fallBackAny && !errors.Is(err, ErrNotFound) {
+			break
+		}
+	}
+	return nil, errs
+}
+
+func (c *Client) fetchFrom(ctx context.Context, base, rel string) ([]byte, error) {
+	switch {
+	case base == "off":
+		return nil, fmt.Errorf("%s: module lookup disabled by proxy setting \"off\"", rel)
+	case base == "direct":
+		return nil, fmt.Errorf("%s: direct module lookups are not supported", rel)
+	case strings.HasPrefix(base, "file://"):
+		u, err := url.Parse(base)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %q: %w", base, err)
+		}
+		data, err := os.ReadFile(filepath.Join(filepath.FromSlash(u.Path), filepath.FromSlash(rel)))
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%s/%s: %w", base, rel, ErrNotFound)
+		}
+		return data, err
+	}
+
+	return c.get(ctx, base+"/"+rel)
+}
+
+func (c *Client) get(ctx context.Context, u string) (data []byte, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", u, err)
+	}
+	if sem := c.limiter(req.URL.Host); sem != nil {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		defer func() { <-sem }()
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", u, err)
+	}
+	defer func() {
+		err = errors.Join(err, resp.Body.Close())
+	}()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusGone:
+		return nil, fmt.Errorf("%s: %w", u, ErrNotFound)
+	default:
+		return nil, fmt.Errorf("unexpected status code for %s: %d", u, resp.StatusCode)
+	}
+	data, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body for %s: %w", u, err)
+	}
+	return data, nil
+}
+
+// better reports whether version v should be preferred over w as the latest version.
+func better(v, w string) bool {
+	vRelease, wRelease := semver.Prerelease(v) == "", semver.Prerelease(w) == ""
+	if vRelease != wRelease {
+		return vRelease
+	}
+	return semver.Compare(v, w) > 0
+}
+
+func versionPath(path, version, suffix string) (string, error) {
+	escPath, err := module.EscapePath(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid module path %s: %w", path, err)
+	}
+	escVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return "", fmt.Errorf("invalid version %s: %w", version, err)
+	}
+	return escPath + "/@v/" + escVersion + suffix, nil
+}
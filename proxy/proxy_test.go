@@ -0,0 +1,205 @@
+package proxy_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fflewddur/pantry/proxy"
+	"github.com/fflewddur/pantry/proxy/proxytest"
+)
+
+var t0 = time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+
+func newFakeProxy(t *testing.T) *proxytest.Server {
+	t.Helper()
+	srv := proxytest.NewServer()
+	t.Cleanup(srv.Close)
+	mods := []struct {
+		path, version string
+		t             time.Time
+	}{
+		{"example.com/foo", "v1.0.0", t0},
+		{"example.com/foo", "v1.1.0", t0.Add(time.Hour)},
+		{"example.com/Bar", "v0.1.0", t0.Add(2 * time.Hour)},
+	}
+	for _, m := range mods {
+		err := srv.AddModule(m.path, m.version, m.t, map[string]string{"README.md": "# " + m.path})
+		if err != nil {
+			t.Fatalf("AddModule(%s, %s): %v", m.path, m.version, err)
+		}
+	}
+	return srv
+}
+
+func TestClient(t *testing.T) {
+	srv := newFakeProxy(t)
+	c, err := proxy.NewClient(srv.ProxyURL(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	info, err := c.Latest(ctx, "example.com/foo")
+	if err != nil {
+		t.Fatalf("Latest: %v", err)
+	}
+	if info.Version != "v1.1.0" || !info.Time.Equal(t0.Add(time.Hour)) {
+		t.Errorf("Latest = %+v, want v1.1.0 at %v", info, t0.Add(time.Hour))
+	}
+
+	info, err = c.Info(ctx, "example.com/Bar", "v0.1.0")
+	if err != nil {
+		t.Fatalf("Info: %v", err)
+	}
+	if info.Version != "v0.1.0" {
+		t.Errorf("Info.Version = %s, want v0.1.0", info.Version)
+	}
+
+	data, err := c.Zip(ctx, "example.com/foo", "v1.0.0")
+	if err != nil {
+		t.Fatalf("Zip: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("zip.NewReader: %v", err)
+	}
+	if len(zr.File) != 2 || zr.File[0].Name != "example.com/foo@v1.0.0/README.md" {
+		t.Errorf("unexpected zip contents: %v", zr.File)
+	}
+
+	_, err = c.Info(ctx, "example.com/foo", "v9.9.9")
+	if !errors.Is(err, proxy.ErrNotFound) {
+		t.Errorf("Info for missing version: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestClientFallback(t *testing.T) {
+	srv := newFakeProxy(t)
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "oops", http.StatusInternalServerError)
+	}))
+	t.Cleanup(broken.Close)
+	missing := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(missing.Close)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		list    string
+		wantErr bool
+	}{
+		{"comma after 404", missing.URL + "," + srv.ProxyURL(), false},
+		{"comma after 500", broken.URL + "," + srv.ProxyURL(), true},
+		{"pipe after 500", broken.URL + "|" + srv.ProxyURL(), false},
+		{"off", "off," + srv.ProxyURL(), true},
+		{"direct", missing.URL + ",direct", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := proxy.NewClient(tt.list, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = c.Latest(ctx, "example.com/foo")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Latest with %q: err = %v, wantErr %v", tt.list, err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := proxy.NewClient("ftp://example.com", nil); err == nil {
+		t.Error("NewClient accepted an ftp:// proxy")
+	}
+}
+
+func TestClientMaxConnsPerHost(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"Version":"v1.0.0"}`))
+	}))
+	t.Cleanup(srv.Close)
+	c, err := proxy.NewClient(srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetMaxConnsPerHost(2)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Info(context.Background(), "example.com/foo", "v1.0.0"); err != nil {
+				t.Errorf("Info: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if maxInFlight > 2 {
+		t.Errorf("saw %d concurrent requests, want at most 2", maxInFlight)
+	}
+}
+
+func TestClientFileProxy(t *testing.T) {
+	srv := newFakeProxy(t)
+	dir := t.TempDir()
+	if err := srv.WriteDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	c, err := proxy.NewClient("file://"+dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// file:// proxies have no @latest endpoint, so the client falls back to @v/list.
+	info, err := c.Latest(ctx, "example.com/foo")
+	if err != nil {
+		t.Fatalf("Latest: %v", err)
+	}
+	if info.Version != "v1.1.0" {
+		t.Errorf("Latest.Version = %s, want v1.1.0", info.Version)
+	}
+	// Upper-case letters in module paths are escaped on disk.
+	if _, err := c.Zip(ctx, "example.com/Bar", "v0.1.0"); err != nil {
+		t.Errorf("Zip: %v", err)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	srv := newFakeProxy(t)
+	idx := proxy.NewIndex(srv.IndexURL(), nil)
+	ctx := context.Background()
+
+	entries, err := idx.Entries(ctx, time.Time{}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Version != "v1.0.0" || entries[1].Version != "v1.1.0" {
+		t.Errorf("Entries(zero, 2) = %v", entries)
+	}
+
+	entries, err = idx.Entries(ctx, t0.Add(time.Hour), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[1].Path != "example.com/Bar" {
+		t.Errorf("Entries(t0+1h, 10) = %v", entries)
+	}
+}
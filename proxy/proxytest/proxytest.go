@@ -0,0 +1,269 @@
+// Package proxytest provides an in-process module proxy and index server, so
+// that code talking to the Go module mirror can be tested without network access.
+package proxytest
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fflewddur/pantry/proxy"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// Server is a fake module proxy and index feed. The proxy is served under
+// ProxyURL and the index feed under IndexURL.
+type Server struct {
+	srv *httptest.Server
+
+	mu      sync.Mutex
+	mods    map[string]map[string]*modVersion // module path -> version -> contents
+	entries []proxy.ModuleEntry               // index feed, ordered by timestamp
+}
+
+type modVersion struct {
+	info proxy.Info
+	mod  []byte
+	zip  []byte
+}
+
+// NewServer starts a new fake proxy. Callers should call Close when finished.
+func NewServer() *Server {
+	s := &Server{
+		mods: make(map[string]map[string]*modVersion),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/index", s.indexHandler)
+	mux.HandleFunc("/proxy/", s.proxyHandler)
+	s.srv = httptest.NewServer(mux)
+	return s
+}
+
+// Close shuts down the server.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// ProxyURL returns the base URL of the fake module proxy, suitable for a GOPROXY list.
+func (s *Server) ProxyURL() string {
+	return s.srv.URL + "/proxy"
+}
+
+// IndexURL returns the URL of the fake index feed.
+func (s *Server) IndexURL() string {
+	return s.srv.URL + "/index"
+}
+
+// AddModule publishes a module version with the given files (keyed by
+// slash-separated path relative to the module root) and appends it to the
+// index feed with timestamp t. A minimal go.mod is added if files lacks one.
+func (s *Server) AddModule(path, version string, t time.Time, files map[string]string) error {
+	if err := module.Check(path, version); err != nil {
+		return err
+	}
+	if _, ok := files["go.mod"]; !ok {
+		files = copyFiles(files)
+		files["go.mod"] = fmt.Sprintf("module %s\n", path)
+	}
+
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		w, err := zw.Create(path + "@" + version + "/" + name)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write([]byte(files[name])); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.mods[path] == nil {
+		s.mods[path] = make(map[string]*modVersion)
+	}
+	s.mods[path][version] = &modVersion{
+		info: proxy.Info{Version: version, Time: t},
+		mod:  []byte(files["go.mod"]),
+		zip:  buf.Bytes(),
+	}
+	s.entries = append(s.entries, proxy.ModuleEntry{Path: path, Version: version, Timestamp: t})
+	sort.SliceStable(s.entries, func(i, j int) bool {
+		return s.entries[i].Timestamp.Before(s.entries[j].Timestamp)
+	})
+	return nil
+}
+
+// WriteDir lays out every published module version under dir following the
+// GOPROXY protocol, so that dir can be used as a file:// proxy.
+func (s *Server) WriteDir(dir string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for path, versions := range s.mods {
+		escPath, err := module.EscapePath(path)
+		if err != nil {
+			return err
+		}
+		vDir := filepath.Join(dir, filepath.FromSlash(escPath), "@v")
+		if err := os.MkdirAll(vDir, os.ModePerm); err != nil {
+			return err
+		}
+		var list strings.Builder
+		for version, mv := range versions {
+			escVersion, err := module.EscapeVersion(version)
+			if err != nil {
+				return err
+			}
+			info, err := json.Marshal(mv.info)
+			if err != nil {
+				return err
+			}
+			for ext, data := range map[string][]byte{".info": info, ".mod": mv.mod, ".zip": mv.zip} {
+				if err := os.WriteFile(filepath.Join(vDir, escVersion+ext), data, 0644); err != nil {
+					return err
+				}
+			}
+			list.WriteString(version + "\n")
+		}
+		if err := os.WriteFile(filepath.Join(vDir, "list"), []byte(list.String()), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyFiles(files map[string]string) map[string]string {
+	c := make(map[string]string, len(files)+1)
+	for k, v := range files {
+		c[k] = v
+	}
+	return c
+}
+
+func (s *Server) indexHandler(w http.ResponseWriter, r *http.Request) {
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			http.Error(w, "invalid since", http.StatusBadRequest)
+			return
+		}
+		since = t
+	}
+	limit := 2000
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, limit)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	enc := json.NewEncoder(w)
+	for _, e := range s.entries {
+		if limit == 0 {
+			break
+		}
+		if e.Timestamp.Before(since) {
+			continue
+		}
+		if err := enc.Encode(e); err != nil {
+			return
+		}
+		limit--
+	}
+}
+
+func (s *Server) proxyHandler(w http.ResponseWriter, r *http.Request) {
+	rel := strings.TrimPrefix(r.URL.Path, "/proxy/")
+	escPath, file, ok := strings.Cut(rel, "/@")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	path, err := module.UnescapePath(escPath)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	versions := s.mods[path]
+	if file == "latest" {
+		latest := ""
+		for v := range versions {
+			if latest == "" || semver.Compare(v, latest) > 0 {
+				latest = v
+			}
+		}
+		if latest == "" {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, versions[latest].info)
+		return
+	}
+	if file == "v/list" {
+		for v := range versions {
+			fmt.Fprintln(w, v)
+		}
+		return
+	}
+
+	file, ok = strings.CutPrefix(file, "v/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	i := strings.LastIndex(file, ".")
+	if i < 0 {
+		http.NotFound(w, r)
+		return
+	}
+	ext := file[i:]
+	version, err := module.UnescapeVersion(strings.TrimSuffix(file, ext))
+	if err != nil || versions[version] == nil {
+		http.NotFound(w, r)
+		return
+	}
+	mv := versions[version]
+	switch ext {
+	case ".info":
+		writeJSON(w, mv.info)
+	case ".mod":
+		_, _ = w.Write(mv.mod)
+	case ".zip":
+		_, _ = w.Write(mv.zip)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,323 @@
+// Package embedded implements search.Searcher in process, so that pantry can
+// run without an external search daemon.
+//
+// Each index is held in memory as an inverted index and persisted to an
+// append-only log, <dir>/<index>.log, with one JSON record per change. Several
+// processes may open the same directory, such as the scanner writing to it
+// while the server reads it: each catches up with the log before searching.
+package embedded
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/fflewddur/pantry/search"
+)
+
+// BM25 ranking parameters
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// Engine is an embedded search engine storing its indexes in a directory.
+type Engine struct {
+	dir string
+
+	mu      sync.Mutex
+	indexes map[string]*index
+}
+
+// index is the in-memory state of one index.
+type index struct {
+	docs     map[uint64]*search.Document
+	postings map[string]map[uint64]int // Term frequencies by term and document
+	lengths  map[uint64]int            // Number of terms in each document
+	totalLen int
+	offset   int64 // Bytes of the log applied so far
+}
+
+// record is one line of an index log.
+type record struct {
+	Op     string            `json:"op"` // "index" or "delete"
+	ID     uint64            `json:"id"`
+	Fields map[string]string `json:"fields,omitempty"`
+	Attrs  map[string]any    `json:"attrs,omitempty"`
+}
+
+// Open returns an engine storing its indexes in dir, creating it if needed.
+func Open(dir string) (*Engine, error) {
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create search directory %s: %w", dir, err)
+	}
+	return &Engine{dir: dir, indexes: make(map[string]*index)}, nil
+}
+
+func (e *Engine) Index(ctx context.Context, name string, doc *search.Document) error {
+	return e.append(name, &record{Op: "index", ID: doc.ID, Fields: doc.Fields, Attrs: doc.Attrs})
+}
+
+func (e *Engine) Delete(ctx context.Context, name string, id uint64) error {
+	return e.append(name, &record{Op: "delete", ID: id})
+}
+
+// append writes r to the log of an index and applies it.
+func (e *Engine) append(name string, r *record) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to encode document %d: %w", r.ID, err)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	f, err := os.OpenFile(e.logPath(name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open index %s: %w", name, err)
+	}
+	_, err = f.Write(append(line, '\n'))
+	err = errors.Join(err, f.Close())
+	if err != nil {
+		return fmt.Errorf("failed to write to index %s: %w", name, err)
+	}
+	// Other processes may have appended records too, so apply everything new
+	_, err = e.sync(name)
+	return err
+}
+
+func (e *Engine) logPath(name string) string {
+	return filepath.Join(e.dir, name+".log")
+}
+
+// sync applies records appended to an index's log since it was last read.
+func (e *Engine) sync(name string) (*index, error) {
+	ix := e.indexes[name]
+	if ix == nil {
+		ix = &index{
+			docs:     make(map[uint64]*search.Document),
+			postings: make(map[string]map[uint64]int),
+			lengths:  make(map[uint64]int),
+		}
+		e.indexes[name] = ix
+	}
+	f, err := os.Open(e.logPath(name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ix, nil // Nothing has been indexed yet
+		}
+		return nil, fmt.Errorf("failed to open index %s: %w", name, err)
+	}
+	defer f.Close()
+	_, err = f.Seek(ix.offset, io.SeekStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index %s: %w", name, err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index %s: %w", name, err)
+	}
+	for {
+		// A line without a newline is still being written
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		line := data[:i]
+		data = data[i+1:]
+		ix.offset += int64(i + 1)
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.UseNumber() // Keep integer attributes exact
+		var r record
+		err := dec.Decode(&r)
+		if err != nil {
+			return nil, fmt.Errorf("corrupt record in index %s at offset %d: %w", name, ix.offset-int64(i+1), err)
+		}
+		ix.remove(r.ID)
+		if r.Op == "index" {
+			ix.add(&search.Document{ID: r.ID, Fields: r.Fields, Attrs: r.Attrs})
+		}
+	}
+	return ix, nil
+}
+
+func (ix *index) add(doc *search.Document) {
+	ix.docs[doc.ID] = doc
+	n := 0
+	for _, text := range doc.Fields {
+		for _, term := range tokenize(text) {
+			p := ix.postings[term]
+			if p == nil {
+				p = make(map[uint64]int)
+				ix.postings[term] = p
+			}
+			p[doc.ID]++
+			n++
+		}
+	}
+	ix.lengths[doc.ID] = n
+	ix.totalLen += n
+}
+
+func (ix *index) remove(id uint64) {
+	doc, ok := ix.docs[id]
+	if !ok {
+		return
+	}
+	for _, text := range doc.Fields {
+		for _, term := range tokenize(text) {
+			p := ix.postings[term]
+			delete(p, id)
+			if len(p) == 0 {
+				delete(ix.postings, term)
+			}
+		}
+	}
+	ix.totalLen -= ix.lengths[id]
+	delete(ix.lengths, id)
+	delete(ix.docs, id)
+}
+
+// tokenize splits text into lowercase words made of letters and digits.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Search returns the documents containing every term of the query text,
+// ranked with BM25.
+func (e *Engine) Search(ctx context.Context, q *search.Query) (*search.Result, error) {
+	start := time.Now()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	ix, err := e.sync(q.Index)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make(map[uint64]float64)
+	terms := tokenize(q.Text)
+	if len(terms) == 0 {
+		for id := range ix.docs {
+			scores[id] = 0
+		}
+	}
+	for i, term := range terms {
+		p := ix.postings[term]
+		idf := math.Log(1 + (float64(len(ix.docs))-float64(len(p))+0.5)/(float64(len(p))+0.5))
+		avgLen := float64(ix.totalLen) / float64(max(len(ix.docs), 1))
+		next := make(map[uint64]float64)
+		for id, tf := range p {
+			prev, ok := scores[id]
+			if i > 0 && !ok {
+				continue // Every term must match
+			}
+			norm := float64(tf) + k1*(1-b+b*float64(ix.lengths[id])/avgLen)
+			next[id] = prev + idf*float64(tf)*(k1+1)/norm
+		}
+		scores = next
+	}
+
+	var hits []search.Hit
+	for id, score := range scores {
+		if matchesFilters(ix.docs[id], q) {
+			hits = append(hits, search.Hit{ID: id, Score: score})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+
+	result := &search.Result{
+		Total:  len(hits),
+		Facets: make(map[string][]search.FacetCount),
+	}
+	for _, attr := range q.Facets {
+		result.Facets[attr] = facetCounts(ix, hits, attr, q.FacetSize)
+	}
+	from := min(q.Offset, len(hits))
+	to := len(hits)
+	if q.Limit > 0 {
+		to = min(from+q.Limit, len(hits))
+	}
+	result.Hits = hits[from:to]
+	result.Took = time.Since(start)
+	return result, nil
+}
+
+// attrValues returns the values of a document attribute formatted for
+// comparison with filter values. Lists have one value per element.
+func attrValues(doc *search.Document, attr string) []string {
+	switch v := doc.Attrs[attr].(type) {
+	case nil:
+		return []string{""}
+	case []string:
+		return v
+	case []any: // A list read back from the log
+		values := make([]string, len(v))
+		for i, e := range v {
+			values[i] = fmt.Sprint(e)
+		}
+		return values
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
+
+func matchesFilters(doc *search.Document, q *search.Query) bool {
+	for attr, values := range q.Include {
+		found := false
+		for _, v := range attrValues(doc, attr) {
+			found = found || slices.Contains(values, v)
+		}
+		if !found {
+			return false
+		}
+	}
+	for attr, values := range q.Exclude {
+		for _, v := range attrValues(doc, attr) {
+			if slices.Contains(values, v) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func facetCounts(ix *index, hits []search.Hit, attr string, size int) []search.FacetCount {
+	counts := make(map[string]int)
+	for _, h := range hits {
+		for _, v := range attrValues(ix.docs[h.ID], attr) {
+			counts[v]++
+		}
+	}
+	facets := make([]search.FacetCount, 0, len(counts))
+	for v, n := range counts {
+		facets = append(facets, search.FacetCount{Value: v, Count: n})
+	}
+	sort.Slice(facets, func(i, j int) bool {
+		if facets[i].Count != facets[j].Count {
+			return facets[i].Count > facets[j].Count
+		}
+		return facets[i].Value < facets[j].Value
+	})
+	if size > 0 && len(facets) > size {
+		facets = facets[:size]
+	}
+	return facets
+}
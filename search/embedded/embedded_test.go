@@ -0,0 +1,169 @@
+package embedded
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/fflewddur/pantry/search"
+)
+
+func ids(hits []search.Hit) []uint64 {
+	var ids []uint64
+	for _, h := range hits {
+		ids = append(ids, h.ID)
+	}
+	return ids
+}
+
+func TestEngine(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	e, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := []*search.Document{
+		{ID: 1, Fields: map[string]string{"path": "example.com/fast", "readme": "A fast HTTP router."}, Attrs: map[string]any{"license": "MIT", "time": int64(1700000000)}},
+		{ID: 2, Fields: map[string]string{"path": "example.com/mux", "readme": "HTTP request router and dispatcher. Router router router."}, Attrs: map[string]any{"license": "BSD-3-Clause"}},
+		{ID: 3, Fields: map[string]string{"path": "example.com/yaml", "readme": "YAML support."}, Attrs: map[string]any{"license": "MIT"}},
+	}
+	for _, doc := range docs {
+		if err := e.Index(ctx, search.Mods, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := e.Search(ctx, &search.Query{Index: search.Mods, Text: "HTTP router", Facets: []string{"license"}, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(res.Hits); !reflect.DeepEqual(got, []uint64{2, 1}) || res.Total != 2 {
+		t.Errorf("HTTP router: hits = %v, total = %d", got, res.Total)
+	}
+	wantFacets := []search.FacetCount{{Value: "BSD-3-Clause", Count: 1}, {Value: "MIT", Count: 1}}
+	if !reflect.DeepEqual(res.Facets["license"], wantFacets) {
+		t.Errorf("license facets = %+v", res.Facets["license"])
+	}
+
+	res, err = e.Search(ctx, &search.Query{Index: search.Mods, Text: "router", Include: map[string][]string{"license": {"MIT"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(res.Hits); !reflect.DeepEqual(got, []uint64{1}) {
+		t.Errorf("router license:MIT: hits = %v", got)
+	}
+	res, err = e.Search(ctx, &search.Query{Index: search.Mods, Exclude: map[string][]string{"license": {"MIT"}}, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(res.Hits); !reflect.DeepEqual(got, []uint64{2}) {
+		t.Errorf("license:-MIT: hits = %v", got)
+	}
+
+	// Replacing and deleting documents
+	err = e.Index(ctx, search.Mods, &search.Document{ID: 3, Fields: map[string]string{"path": "example.com/yaml", "readme": "YAML router config."}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Delete(ctx, search.Mods, 2); err != nil {
+		t.Fatal(err)
+	}
+	res, err = e.Search(ctx, &search.Query{Index: search.Mods, Text: "router", Offset: 1, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Total != 2 || len(res.Hits) != 1 {
+		t.Errorf("router page 2: hits = %v, total = %d", ids(res.Hits), res.Total)
+	}
+	if res, _ := e.Search(ctx, &search.Query{Index: search.Mods, Text: "support"}); res.Total != 0 {
+		t.Errorf("replaced document still matches its old text")
+	}
+
+	// Another engine on the same directory sees every change, and exact integer attributes
+	reader, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = reader.Search(ctx, &search.Query{Index: search.Mods, Text: "router", Include: map[string][]string{"time": {"1700000000"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(res.Hits); !reflect.DeepEqual(got, []uint64{1}) {
+		t.Errorf("reopened engine: hits = %v", got)
+	}
+
+	// A partially written record is left for the next sync
+	f, err := os.OpenFile(filepath.Join(dir, search.Mods+".log"), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"op":"delete","id":1`); err != nil {
+		t.Fatal(err)
+	}
+	res, err = reader.Search(ctx, &search.Query{Index: search.Mods, Text: "router"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Total != 2 {
+		t.Errorf("partial record: total = %d, want 2", res.Total)
+	}
+	if _, err := f.WriteString("}\n"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	res, err = reader.Search(ctx, &search.Query{Index: search.Mods, Text: "router"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(res.Hits); !reflect.DeepEqual(got, []uint64{3}) {
+		t.Errorf("completed record: hits = %v", got)
+	}
+
+	if res, err := e.Search(ctx, &search.Query{Index: search.Symbols, Text: "Marshal"}); err != nil || res.Total != 0 {
+		t.Errorf("empty index: %v, %v", res, err)
+	}
+}
+
+// TestListAttrs checks that a list attribute matches a filter if any of its
+// values does, including after the index is read back from its log.
+func TestListAttrs(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	e, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := []*search.Document{
+		{ID: 1, Attrs: map[string]any{"licenses": []string{"MIT"}}},
+		{ID: 2, Attrs: map[string]any{"licenses": []string{"MIT", "GPL-3.0"}}},
+		{ID: 3},
+	}
+	for _, doc := range docs {
+		if err := e.Index(ctx, search.Mods, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reopened, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []*Engine{e, reopened} {
+		res, err := e.Search(ctx, &search.Query{Index: search.Mods, Exclude: map[string][]string{"licenses": {"GPL-3.0"}}, Facets: []string{"licenses"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ids(res.Hits); !reflect.DeepEqual(got, []uint64{1, 3}) {
+			t.Errorf("licenses:-GPL-3.0: hits = %v", got)
+		}
+		res, err = e.Search(ctx, &search.Query{Index: search.Mods, Include: map[string][]string{"licenses": {"GPL-3.0"}}, Facets: []string{"licenses"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ids(res.Hits); !reflect.DeepEqual(got, []uint64{2}) || !reflect.DeepEqual(res.Facets["licenses"], []search.FacetCount{{Value: "GPL-3.0", Count: 1}, {Value: "MIT", Count: 1}}) {
+			t.Errorf("licenses:GPL-3.0: hits = %v, facets = %v", got, res.Facets["licenses"])
+		}
+	}
+}
@@ -0,0 +1,219 @@
+// Package manticore implements search.Searcher on top of a Manticore Search
+// server, using its HTTP JSON API.
+package manticore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"time"
+
+	"github.com/fflewddur/pantry/search"
+	openapi "github.com/manticoresoftware/manticoresearch-go"
+)
+
+// listAttrs are the attributes holding lists of strings. Manticore only has
+// multi-valued integer attributes, so these hold the CRC32 checksum of each
+// string instead, as manticore.conf computes them with crc32ieee, and filters
+// on them are checksummed the same way. They cannot be faceted on.
+var listAttrs = map[string]bool{"license_ids": true}
+
+// checksums returns the CRC32 checksums of values.
+func checksums(values []string) []uint64 {
+	sums := make([]uint64, len(values))
+	for i, v := range values {
+		sums[i] = uint64(crc32.ChecksumIEEE([]byte(v)))
+	}
+	return sums
+}
+
+// Searcher queries and updates Manticore tables.
+type Searcher struct {
+	client *openapi.APIClient
+	tables map[string]string
+}
+
+// New returns a Searcher for the Manticore server at url, or at the client's
+// default address if url is empty. The tables map pantry's index names (such
+// as search.Mods) to Manticore tables. Writes to an index without a table are
+// ignored, since plain tables are rebuilt from the database by the indexer.
+func New(url string, tables map[string]string) *Searcher {
+	cfg := openapi.NewConfiguration()
+	if url != "" {
+		cfg.Servers = openapi.ServerConfigurations{{URL: url}}
+	}
+	return &Searcher{client: openapi.NewAPIClient(cfg), tables: tables}
+}
+
+func (s *Searcher) Index(ctx context.Context, index string, doc *search.Document) error {
+	table, ok := s.tables[index]
+	if !ok {
+		return nil
+	}
+	fields := make(map[string]interface{}, len(doc.Fields)+len(doc.Attrs))
+	for k, v := range doc.Fields {
+		fields[k] = v
+	}
+	for k, v := range doc.Attrs {
+		if values, ok := v.([]string); ok && listAttrs[k] {
+			fields[k] = checksums(values)
+			continue
+		}
+		fields[k] = v
+	}
+	req := openapi.NewInsertDocumentRequest(table, fields)
+	req.SetId(doc.ID)
+	_, httpResp, err := s.client.IndexAPI.Replace(ctx).InsertDocumentRequest(*req).Execute()
+	if err != nil {
+		return fmt.Errorf("failed to index document %d in %s (HTTP response: %v): %w", doc.ID, table, httpResp, err)
+	}
+	return nil
+}
+
+func (s *Searcher) Delete(ctx context.Context, index string, id uint64) error {
+	table, ok := s.tables[index]
+	if !ok {
+		return nil
+	}
+	req := openapi.NewDeleteDocumentRequest(table)
+	req.SetId(id)
+	_, httpResp, err := s.client.IndexAPI.Delete(ctx).DeleteDocumentRequest(*req).Execute()
+	if err != nil {
+		return fmt.Errorf("failed to delete document %d from %s (HTTP response: %v): %w", id, table, httpResp, err)
+	}
+	return nil
+}
+
+func (s *Searcher) Search(ctx context.Context, q *search.Query) (*search.Result, error) {
+	table, ok := s.tables[q.Index]
+	if !ok {
+		return nil, fmt.Errorf("no Manticore table for index %q", q.Index)
+	}
+	searchReq := openapi.NewSearchRequest(table)
+	searchReq.SetLimit(int32(q.Limit))
+	searchReq.SetOffset(int32(q.Offset))
+	searchReq.SetQuery(*buildQuery(q))
+	if len(q.Facets) > 0 {
+		aggs := make(map[string]openapi.Aggregation, len(q.Facets))
+		for _, attr := range q.Facets {
+			terms := openapi.NewAggTerms(attr)
+			if q.FacetSize > 0 {
+				terms.SetSize(int32(q.FacetSize))
+			}
+			agg := openapi.NewAggregation()
+			agg.SetTerms(*terms)
+			aggs[attr] = *agg
+		}
+		searchReq.SetAggs(aggs)
+	}
+	searchResp, httpResp, err := s.client.SearchAPI.Search(ctx).SearchRequest(*searchReq).Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to search %s (HTTP response: %v): %w", table, httpResp, err)
+	}
+
+	result := &search.Result{
+		Took:   time.Duration(searchResp.GetTook()) * time.Millisecond,
+		Facets: make(map[string][]search.FacetCount),
+	}
+	hits, ok := searchResp.GetHitsOk()
+	if ok {
+		for _, hit := range hits.Hits {
+			if hit.Id == nil {
+				continue
+			}
+			h := search.Hit{ID: *hit.Id}
+			if hit.Score != nil {
+				h.Score = float64(*hit.Score)
+			}
+			result.Hits = append(result.Hits, h)
+		}
+		result.Total = int(hits.GetTotal())
+	}
+	for _, attr := range q.Facets {
+		result.Facets[attr], err = facetCounts(searchResp.GetAggregations()[attr])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s facet: %w", attr, err)
+		}
+	}
+	warnings, ok := searchResp.GetWarningOk()
+	if ok {
+		result.Warning = fmt.Sprint(warnings)
+	}
+	return result, nil
+}
+
+// buildQuery translates q into a Manticore query, turning attribute filters
+// into a bool query around the full-text query.
+func buildQuery(q *search.Query) *openapi.SearchQuery {
+	query := openapi.NewSearchQuery()
+	if len(q.Include) == 0 && len(q.Exclude) == 0 {
+		query.SetQueryString(q.Text)
+		return query
+	}
+	var must []openapi.QueryFilter
+	var mustNot []*openapi.QueryFilter
+	if q.Text != "" {
+		f := openapi.NewQueryFilter()
+		f.SetQueryString(q.Text)
+		must = append(must, *f)
+	}
+	for attr, values := range q.Include {
+		f := openapi.NewQueryFilter()
+		if listAttrs[attr] {
+			f.SetIn(map[string]interface{}{attr: checksums(values)})
+		} else {
+			f.SetIn(map[string]interface{}{attr: values})
+		}
+		must = append(must, *f)
+	}
+	for attr, values := range q.Exclude {
+		for _, v := range values {
+			f := openapi.NewQueryFilter()
+			if listAttrs[attr] {
+				f.SetEquals(map[string]interface{}{attr: checksums([]string{v})[0]})
+			} else {
+				f.SetEquals(map[string]interface{}{attr: v})
+			}
+			mustNot = append(mustNot, f)
+		}
+	}
+	boolFilter := openapi.NewBoolFilter()
+	boolFilter.SetMust(must)
+	boolFilter.SetMustNot(mustNot)
+	query.SetBool(*boolFilter)
+	return query
+}
+
+// facetCounts reads the buckets of a terms aggregation.
+func facetCounts(agg interface{}) ([]search.FacetCount, error) {
+	if agg == nil {
+		return nil, nil
+	}
+	// Aggregations are untyped in the client, so round-trip them through JSON
+	data, err := json.Marshal(agg)
+	if err != nil {
+		return nil, err
+	}
+	var terms struct {
+		Buckets []struct {
+			Key      json.RawMessage `json:"key"`
+			DocCount int             `json:"doc_count"`
+		} `json:"buckets"`
+	}
+	err = json.Unmarshal(data, &terms)
+	if err != nil {
+		return nil, err
+	}
+	counts := make([]search.FacetCount, 0, len(terms.Buckets))
+	for _, b := range terms.Buckets {
+		// Keys are strings for string attributes and numbers otherwise
+		var key interface{}
+		err := json.Unmarshal(b.Key, &key)
+		if err != nil {
+			return nil, err
+		}
+		counts = append(counts, search.FacetCount{Value: fmt.Sprint(key), Count: b.DocCount})
+	}
+	return counts, nil
+}
@@ -0,0 +1,59 @@
+package manticore
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/fflewddur/pantry/search"
+)
+
+func TestFacetCounts(t *testing.T) {
+	var agg interface{}
+	err := json.Unmarshal([]byte(`{"buckets": [{"key": "MIT", "doc_count": 12}, {"key": "", "doc_count": 3}, {"key": 1, "doc_count": 2}]}`), &agg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts, err := facetCounts(agg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []search.FacetCount{{Value: "MIT", Count: 12}, {Value: "", Count: 3}, {Value: "1", Count: 2}}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("facetCounts = %+v, want %+v", counts, want)
+	}
+	if counts, err := facetCounts(nil); counts != nil || err != nil {
+		t.Errorf("facetCounts(nil) = %v, %v", counts, err)
+	}
+}
+
+func TestBuildQuery(t *testing.T) {
+	tests := []struct {
+		q    search.Query
+		want string
+	}{
+		{search.Query{Text: "router"}, `{"query_string":"router"}`},
+		{
+			search.Query{Text: "router", Include: map[string][]string{"license": {"MIT", "ISC"}}},
+			`{"bool":{"must":[{"query_string":"router"},{"in":{"license":["MIT","ISC"]}}]}}`,
+		},
+		{
+			search.Query{Exclude: map[string][]string{"license": {"GPL-3.0"}}},
+			`{"bool":{"must_not":[{"equals":{"license":"GPL-3.0"}}]}}`,
+		},
+		{
+			// Lists of licenses are filtered on their checksums
+			search.Query{Include: map[string][]string{"license_ids": {"MIT"}}, Exclude: map[string][]string{"license_ids": {"GPL-3.0"}}},
+			`{"bool":{"must":[{"in":{"license_ids":[3401492000]}}],"must_not":[{"equals":{"license_ids":3571858588}}]}}`,
+		},
+	}
+	for _, test := range tests {
+		data, err := json.Marshal(buildQuery(&test.q))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != test.want {
+			t.Errorf("buildQuery(%+v) = %s, want %s", test.q, data, test.want)
+		}
+	}
+}
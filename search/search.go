@@ -0,0 +1,70 @@
+// Package search defines the interface between pantry and its full-text
+// search engine. Documents are indexed by the scanner and queried by the
+// server; the id of each document is the database id of the row it
+// describes, so that callers can load the full record after a search.
+package search
+
+import (
+	"context"
+	"time"
+)
+
+// Names of the indexes pantry maintains.
+const (
+	Mods    = "mods"    // The latest version of each module
+	Symbols = "symbols" // Exported identifiers in the latest version of each module
+)
+
+// Document is one searchable item in an index.
+type Document struct {
+	ID     uint64
+	Fields map[string]string // Full-text fields
+	// Attributes that can be filtered and faceted on: strings, integers,
+	// booleans, or lists of strings. A list matches a filter if any of its
+	// values does.
+	Attrs map[string]any
+}
+
+// Query describes a search of one index.
+type Query struct {
+	Index   string
+	Text    string              // Full-text query; an empty query matches every document
+	Include map[string][]string // Attribute values a document must have one of
+	Exclude map[string][]string // Attribute values a document must not have
+	Facets  []string            // Attributes to count matches by
+	// Maximum number of values returned for each facet
+	FacetSize int
+	Offset    int
+	Limit     int
+}
+
+// Result is one page of matches for a query.
+type Result struct {
+	Hits    []Hit
+	Total   int                     // Number of matches across all pages
+	Facets  map[string][]FacetCount // Match counts for each requested facet, most common first
+	Took    time.Duration
+	Warning string // Non-fatal problem reported by the engine, if any
+}
+
+// Hit is a matching document.
+type Hit struct {
+	ID    uint64
+	Score float64 // Relevance; only comparable between hits of the same query
+}
+
+// FacetCount is the number of matches with one value of an attribute.
+type FacetCount struct {
+	Value string
+	Count int
+}
+
+// Searcher is implemented by search engines.
+type Searcher interface {
+	// Index adds doc to an index, replacing any document with the same id.
+	Index(ctx context.Context, index string, doc *Document) error
+	// Delete removes the document with the given id from an index.
+	Delete(ctx context.Context, index string, id uint64) error
+	// Search returns the documents matching q.
+	Search(ctx context.Context, q *Query) (*Result, error)
+}
@@ -0,0 +1,40 @@
+// Package searchenv opens the search engine that pantry's commands are
+// configured to use through environment variables. It is separate from
+// package search, since it imports every implementation of search.Searcher.
+package searchenv
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fflewddur/pantry/search"
+	"github.com/fflewddur/pantry/search/embedded"
+	"github.com/fflewddur/pantry/search/manticore"
+)
+
+// Open returns the search engine selected by PANTRY_SEARCH:
+//
+//   - "manticore" (the default) uses the Manticore server at
+//     PANTRY_MANTICORE_URL and the given tables, as described by
+//     manticore.New. Open returns nil if tables is empty, since there would be
+//     nothing to read or write.
+//   - "embedded" stores its indexes in PANTRY_SEARCH_DIR, which defaults to
+//     data/search.
+func Open(tables map[string]string) (search.Searcher, error) {
+	switch engine := os.Getenv("PANTRY_SEARCH"); engine {
+	case "", "manticore":
+		if len(tables) == 0 {
+			return nil, nil
+		}
+		return manticore.New(os.Getenv("PANTRY_MANTICORE_URL"), tables), nil
+	case "embedded":
+		dir := os.Getenv("PANTRY_SEARCH_DIR")
+		if dir == "" {
+			dir = filepath.Join("data", "search")
+		}
+		return embedded.Open(dir)
+	default:
+		return nil, fmt.Errorf("unknown search engine %q in PANTRY_SEARCH", engine)
+	}
+}
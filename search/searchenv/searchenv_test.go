@@ -0,0 +1,35 @@
+package searchenv
+
+import (
+	"testing"
+
+	"github.com/fflewddur/pantry/search"
+	"github.com/fflewddur/pantry/search/embedded"
+	"github.com/fflewddur/pantry/search/manticore"
+)
+
+func TestOpen(t *testing.T) {
+	tables := map[string]string{search.Mods: "mods"}
+	t.Setenv("PANTRY_SEARCH", "")
+	if s, err := Open(tables); err != nil {
+		t.Errorf("Open() = %v, %v; want Manticore by default", s, err)
+	} else if _, ok := s.(*manticore.Searcher); !ok {
+		t.Errorf("Open() = %T; want Manticore by default", s)
+	}
+	if s, err := Open(nil); s != nil || err != nil {
+		t.Errorf("Open(nil) = %v, %v; want nil without Manticore tables", s, err)
+	}
+
+	t.Setenv("PANTRY_SEARCH", "embedded")
+	t.Setenv("PANTRY_SEARCH_DIR", t.TempDir())
+	if s, err := Open(nil); err != nil {
+		t.Errorf("embedded: Open() = %v, %v", s, err)
+	} else if _, ok := s.(*embedded.Engine); !ok {
+		t.Errorf("embedded: Open() = %T", s)
+	}
+
+	t.Setenv("PANTRY_SEARCH", "elastic")
+	if _, err := Open(tables); err == nil {
+		t.Error("Open accepted an unknown engine")
+	}
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Job states.
+const (
+	JobPending = "pending" // Not attempted yet
+	JobFailed  = "failed"  // Failed at least once and waiting to be retried
+	JobDead    = "dead"    // Failed too many times and no longer retried
+)
+
+// Job is a module waiting in the scanner's queue.
+type Job struct {
+	Path        string
+	Version     string // "" for the latest version
+	State       string // One of JobPending, JobFailed, or JobDead
+	Attempts    int    // Number of failed attempts
+	LastError   string // Why the latest attempt failed
+	NextAttempt time.Time
+}
+
+func (c *conn) AddJob(ctx context.Context, path, version string, now time.Time) error {
+	_, err := c.Exec(ctx, `INSERT INTO jobs (path, version, state, next_attempt) VALUES ($1, $2, $3, $4)
+	ON CONFLICT (path, version) DO UPDATE SET state = $3, attempts = 0, next_attempt = $4, updated_at = CURRENT_TIMESTAMP
+	WHERE jobs.state = $5;`, path, version, JobPending, now.UTC(), JobDead)
+	return err
+}
+
+func (c *conn) DueJobs(ctx context.Context, now time.Time, limit int) ([]*Job, error) {
+	rows, err := c.Query(ctx, `SELECT path, version, state, attempts, last_error, next_attempt FROM jobs
+	WHERE state IN ($1, $2) AND next_attempt <= $3 ORDER BY next_attempt, path, version LIMIT $4`, JobPending, JobFailed, now.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	return CollectRows(rows, scanJob)
+}
+
+func (c *conn) CompleteJob(ctx context.Context, path, version string) error {
+	_, err := c.Exec(ctx, `DELETE FROM jobs WHERE path = $1 AND version = $2;`, path, version)
+	return err
+}
+
+func (c *conn) FailJob(ctx context.Context, path, version, reason string, next time.Time) error {
+	state := JobFailed
+	if next.IsZero() {
+		state = JobDead
+	}
+	_, err := c.Exec(ctx, `UPDATE jobs SET state = $3, attempts = attempts + 1, last_error = $4, next_attempt = $5, updated_at = CURRENT_TIMESTAMP
+	WHERE path = $1 AND version = $2;`, path, version, state, reason, next.UTC())
+	return err
+}
+
+func (c *conn) JobsInState(ctx context.Context, state string) ([]*Job, error) {
+	rows, err := c.Query(ctx, `SELECT path, version, state, attempts, last_error, next_attempt FROM jobs WHERE state = $1 ORDER BY path, version`, state)
+	if err != nil {
+		return nil, err
+	}
+	return CollectRows(rows, scanJob)
+}
+
+func scanJob(rows *sql.Rows) (*Job, error) {
+	j := &Job{}
+	var lastError sql.NullString
+	err := rows.Scan(&j.Path, &j.Version, &j.State, &j.Attempts, &lastError, &j.NextAttempt)
+	j.LastError = lastError.String
+	return j, err
+}
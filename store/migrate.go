@@ -0,0 +1,173 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Migration is one versioned step in the evolution of the schema. Applied
+// migrations are recorded in the schema_migrations table.
+type Migration struct {
+	Version int
+	Name    string
+	Up      []string // Statements applying the migration
+	Down    []string // Statements reverting it
+	// Adopt, if set, inspects the database before Up runs and returns
+	// statements to run after it, to repair tables that were created before
+	// migrations existed and that Up leaves as they are.
+	Adopt func(ctx context.Context, q Querier) ([]string, error)
+}
+
+// ErrSchemaOutdated is returned by Check when migrations are pending.
+var ErrSchemaOutdated = errors.New("database schema is out of date")
+
+// initMigrations creates the table recording applied migrations.
+func initMigrations(ctx context.Context, s Store) error {
+	_, err := s.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER NOT NULL PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP);`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+// SchemaVersion returns the version of the latest migration applied to s, or
+// 0 if there is none.
+func SchemaVersion(ctx context.Context, s Store) (int, error) {
+	err := initMigrations(ctx, s)
+	if err != nil {
+		return 0, err
+	}
+	var version int
+	err = s.QueryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	return version, err
+}
+
+// Pending returns the migrations that have not been applied to s yet.
+func Pending(ctx context.Context, s Store) ([]Migration, error) {
+	version, err := SchemaVersion(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, m := range s.Migrations() {
+		if m.Version > version {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// Check returns an error wrapping ErrSchemaOutdated if s needs migrating.
+// The scanner and the server call it before they start.
+func Check(ctx context.Context, s Store) error {
+	pending, err := Pending(ctx, s)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		migrations := s.Migrations()
+		return fmt.Errorf("%w: at version %d, want %d; run `go run ./cmd/migrate up`", ErrSchemaOutdated, pending[0].Version-1, migrations[len(migrations)-1].Version)
+	}
+	return nil
+}
+
+// OpenChecked opens the database named by DATABASE_URL, like OpenEnv, and
+// fails unless it has been migrated to the latest schema.
+func OpenChecked(ctx context.Context) (Store, error) {
+	s, err := OpenEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	err = Check(ctx, s)
+	if err != nil {
+		return nil, errors.Join(err, s.Close())
+	}
+	return s, nil
+}
+
+// Up applies every pending migration in order and returns the ones applied.
+func Up(ctx context.Context, s Store) ([]Migration, error) {
+	pending, err := Pending(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	for i, m := range pending {
+		stmts := m.Up
+		if m.Adopt != nil {
+			repairs, err := m.Adopt(ctx, s)
+			if err != nil {
+				return pending[:i], fmt.Errorf("migration %d (%s): failed to inspect the database: %w", m.Version, m.Name, err)
+			}
+			stmts = append(slices.Clip(stmts), repairs...)
+		}
+		err := runStatements(ctx, s, stmts, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name)
+		if err != nil {
+			return pending[:i], fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+	}
+	return pending, nil
+}
+
+// Down reverts the latest applied migration and returns it, or returns nil if
+// no migration has been applied.
+func Down(ctx context.Context, s Store) (*Migration, error) {
+	version, err := SchemaVersion(ctx, s)
+	if err != nil || version == 0 {
+		return nil, err
+	}
+	var m *Migration
+	for _, candidate := range s.Migrations() {
+		if candidate.Version == version {
+			m = &candidate
+		}
+	}
+	if m == nil {
+		return nil, fmt.Errorf("database is at version %d, which this version of pantry does not know", version)
+	}
+	err = runStatements(ctx, s, m.Down, `DELETE FROM schema_migrations WHERE version = $1`, m.Version)
+	if err != nil {
+		return nil, fmt.Errorf("reverting migration %d (%s): %w", m.Version, m.Name, err)
+	}
+	return m, nil
+}
+
+// runStatements runs stmts, then the statement record with args, which
+// records the change in schema_migrations. If s supports it, they all run in
+// one transaction, so a failed migration leaves the schema as it was.
+// Otherwise each runs in its own transaction.
+func runStatements(ctx context.Context, s Store, stmts []string, record string, args ...any) error {
+	if s.TransactionalDDL() {
+		return s.InTx(ctx, func(tx Tx) error {
+			for _, stmt := range stmts {
+				_, err := tx.Exec(ctx, stmt)
+				if err != nil {
+					return err
+				}
+			}
+			_, err := tx.Exec(ctx, record, args...)
+			return err
+		})
+	}
+	for _, stmt := range stmts {
+		err := s.InTx(ctx, func(tx Tx) error {
+			_, err := tx.Exec(ctx, stmt)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	err := s.InTx(ctx, func(tx Tx) error {
+		_, err := tx.Exec(ctx, record, args...)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record the migration: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMigrationVersions(t *testing.T) {
+	// Both databases must go through the same schema versions
+	if len(postgresMigrations) != len(sqliteMigrations) {
+		t.Fatalf("%d CockroachDB migrations but %d SQLite migrations", len(postgresMigrations), len(sqliteMigrations))
+	}
+	for i := range postgresMigrations {
+		pm, sm := postgresMigrations[i], sqliteMigrations[i]
+		if pm.Version != i+1 || sm.Version != i+1 {
+			t.Errorf("migration %d has versions %d and %d, want %d", i, pm.Version, sm.Version, i+1)
+		}
+		if len(pm.Up) == 0 || len(pm.Down) == 0 || len(sm.Up) == 0 || len(sm.Down) == 0 {
+			t.Errorf("migration %d is missing up or down statements", i+1)
+		}
+	}
+}
+
+func TestMigrateSQLite(t *testing.T) {
+	ctx := context.Background()
+	s := openEmptyStore(t)
+	latest := sqliteMigrations[len(sqliteMigrations)-1].Version
+
+	err := Check(ctx, s)
+	if !errors.Is(err, ErrSchemaOutdated) {
+		t.Fatalf("Check on an empty database: err = %v, want ErrSchemaOutdated", err)
+	}
+	applied, err := Up(ctx, s)
+	if err != nil || len(applied) != len(sqliteMigrations) {
+		t.Fatalf("Up applied %d migrations, err = %v", len(applied), err)
+	}
+	err = Check(ctx, s)
+	if err != nil {
+		t.Fatalf("Check after Up: %v", err)
+	}
+	applied, err = Up(ctx, s)
+	if err != nil || len(applied) != 0 {
+		t.Fatalf("second Up applied %d migrations, err = %v", len(applied), err)
+	}
+
+	m, err := Down(ctx, s)
+	if err != nil || m == nil || m.Version != latest {
+		t.Fatalf("Down = %v, %v; want migration %d", m, err, latest)
+	}
+	version, err := SchemaVersion(ctx, s)
+	if err != nil || version != latest-1 {
+		t.Errorf("SchemaVersion after Down = %d, %v; want %d", version, err, latest-1)
+	}
+	for version > 0 {
+		_, err = Down(ctx, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		version--
+	}
+	_, err = s.Exec(ctx, `SELECT count(*) FROM mods`)
+	if err == nil {
+		t.Error("mods table still exists after reverting every migration")
+	}
+	m, err = Down(ctx, s)
+	if m != nil || err != nil {
+		t.Errorf("Down on an empty schema = %v, %v; want nil, nil", m, err)
+	}
+	_, err = Up(ctx, s)
+	if err != nil {
+		t.Fatalf("Up after reverting: %v", err)
+	}
+}
+
+// migrations replaces the migrations of a Store.
+type migrations struct {
+	Store
+	list []Migration
+}
+
+func (m *migrations) Migrations() []Migration {
+	return m.list
+}
+
+// TestMigrateSQLiteAtomic checks that a migration failing halfway, after it
+// dropped a table, leaves the schema and its recorded version as they were.
+func TestMigrateSQLiteAtomic(t *testing.T) {
+	ctx := context.Background()
+	s := openEmptyStore(t)
+	_, err := Up(ctx, &migrations{Store: s, list: sqliteMigrations[:1]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Exec(ctx, `INSERT INTO mods (path, version) VALUES ($1, $2)`, "example.com/a", "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	broken := Migration{
+		Version: 2,
+		Name:    "broken",
+		Up:      []string{`DROP TABLE mods;`, `ALTER TABLE missing RENAME TO mods;`},
+		Down:    []string{`SELECT 1;`},
+	}
+	_, err = Up(ctx, &migrations{Store: s, list: []Migration{sqliteMigrations[0], broken}})
+	if err == nil {
+		t.Fatal("Up did not report the failed migration")
+	}
+	version, err := SchemaVersion(ctx, s)
+	if err != nil || version != 1 {
+		t.Errorf("SchemaVersion after the failed migration = %d, %v; want 1", version, err)
+	}
+	var path string
+	err = s.QueryRow(ctx, `SELECT path FROM mods`).Scan(&path)
+	if err != nil || path != "example.com/a" {
+		t.Errorf("mods after the failed migration: %q, %v", path, err)
+	}
+}
+
+// TestMigrateAdopt checks that the statements returned by Adopt run after Up,
+// and that Adopt sees the database as it was before Up.
+func TestMigrateAdopt(t *testing.T) {
+	ctx := context.Background()
+	adopt := Migration{
+		Version: 1,
+		Name:    "adopt",
+		Up:      []string{`CREATE TABLE IF NOT EXISTS legacy (a INTEGER, b INTEGER);`},
+		Down:    []string{`DROP TABLE legacy;`},
+		Adopt: func(ctx context.Context, q Querier) ([]string, error) {
+			var n int
+			err := q.QueryRow(ctx, `SELECT count(*) FROM sqlite_master WHERE name = 'legacy'`).Scan(&n)
+			if err != nil || n == 0 {
+				return nil, err
+			}
+			return []string{`ALTER TABLE legacy ADD COLUMN b INTEGER;`}, nil
+		},
+	}
+	for _, legacy := range []bool{false, true} {
+		s := openEmptyStore(t)
+		if legacy {
+			_, err := s.Exec(ctx, `CREATE TABLE legacy (a INTEGER);`)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		_, err := Up(ctx, &migrations{Store: s, list: []Migration{adopt}})
+		if err != nil {
+			t.Fatalf("legacy = %v: Up: %v", legacy, err)
+		}
+		_, err = s.Exec(ctx, `INSERT INTO legacy (a, b) VALUES (1, 2)`)
+		if err != nil {
+			t.Errorf("legacy = %v: table was not repaired: %v", legacy, err)
+		}
+	}
+}
+
+// TestMigrateCockroachModsKey checks that migrating a CockroachDB database
+// created by an earlier version of the server, whose mods table had no
+// primary key, keys mods on id.
+func TestMigrateCockroachModsKey(t *testing.T) {
+	dbURL := os.Getenv("PANTRY_TEST_COCKROACH_URL")
+	if dbURL == "" {
+		t.Skip("Skipping CockroachDB test; set PANTRY_TEST_COCKROACH_URL to run it")
+	}
+	ctx := context.Background()
+	admin, err := Open(dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+	name := fmt.Sprintf("pantry_test_%d", time.Now().UnixNano())
+	_, err = admin.Exec(ctx, `CREATE DATABASE `+name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Exec(ctx, `DROP DATABASE `+name+` CASCADE`)
+	u, err := url.Parse(dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.Path = "/" + name
+	s, err := Open(u.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// The mods table as the server used to create it
+	_, err = s.Exec(ctx, `CREATE TABLE mods (
+	id INT64 DEFAULT unique_rowid(),
+	path TEXT NOT NULL UNIQUE,
+	version TEXT NOT NULL,
+	readme TEXT,
+	docs TEXT,
+	time TIMESTAMP);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Exec(ctx, `INSERT INTO mods (path, version) VALUES ($1, $2)`, "example.com/a", "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _, err := primaryKey(ctx, s, "mods")
+	if err != nil || slices.Equal(key, []string{"id"}) {
+		t.Fatalf("primary key of the old mods table = %v, %v", key, err)
+	}
+
+	_, err = Up(ctx, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _, err = primaryKey(ctx, s, "mods")
+	if err != nil || !slices.Equal(key, []string{"id"}) {
+		t.Errorf("primary key of mods after migrating = %v, %v; want [id]", key, err)
+	}
+	_, version, err := s.LatestVersion(ctx, "example.com/a")
+	if err != nil || version != "v1.0.0" {
+		t.Errorf("LatestVersion after migrating = %q, %v", version, err)
+	}
+}
@@ -0,0 +1,227 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/fflewddur/pantry/extract"
+	"golang.org/x/mod/modfile"
+)
+
+// sqlDB is the part of *sql.DB and *sql.Tx used to run statements.
+type sqlDB interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// conn adapts a *sql.DB or *sql.Tx to Querier and implements Modules on top
+// of it with SQL that both databases understand.
+type conn struct {
+	db       sqlDB
+	rebind   func(query string) string // Rewrites $1-style placeholders for the driver
+	licenses func(ids []string) any    // Encodes a list of SPDX IDs for the licenses column
+}
+
+func (c *conn) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return c.db.ExecContext(ctx, c.rebind(query), args...)
+}
+
+func (c *conn) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return c.db.QueryContext(ctx, c.rebind(query), args...)
+}
+
+func (c *conn) QueryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	return c.db.QueryRowContext(ctx, c.rebind(query), args...)
+}
+
+func (c *conn) LatestVersion(ctx context.Context, path string) (int64, string, error) {
+	var id int64
+	var version string
+	err := c.QueryRow(ctx, `SELECT id, version FROM mods WHERE path = $1`, path).Scan(&id, &version)
+	return id, version, err
+}
+
+func (c *conn) HasVersion(ctx context.Context, path, version string) (bool, error) {
+	var n int
+	err := c.QueryRow(ctx, `SELECT count(*) FROM modversions WHERE path = $1 AND version = $2`, path, version).Scan(&n)
+	return n > 0, err
+}
+
+func (c *conn) PutVersion(ctx context.Context, m *Module) error {
+	_, err := c.Exec(ctx, `INSERT INTO modversions (path, version, readme, docs, time) VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (path, version) DO UPDATE SET readme = $3, docs = $4, time = $5, updated_at = CURRENT_TIMESTAMP;`, m.Path, m.Version, m.Readme, m.Docs, m.Time)
+	return err
+}
+
+func (c *conn) PutModule(ctx context.Context, m *Module) error {
+	return c.QueryRow(ctx, `INSERT INTO mods (path, version, readme, docs, time) VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (path) DO UPDATE SET version = $2, readme = $3, docs = $4, time = $5 RETURNING id;`, m.Path, m.Version, m.Readme, m.Docs, m.Time).Scan(&m.ID)
+}
+
+func (c *conn) PutMeta(ctx context.Context, meta *Meta) error {
+	_, err := c.Exec(ctx, `INSERT INTO modsmeta (id, license, licenses, policy_status, redistributable) VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (id) DO UPDATE SET license = $2, licenses = $3, policy_status = $4, redistributable = $5;`, meta.ID, meta.License, c.licenses(meta.Licenses), meta.PolicyStatus, meta.Redistributable)
+	return err
+}
+
+func (c *conn) PutGoMod(ctx context.Context, path, version string, f *modfile.File) error {
+	for _, table := range []string{"modrequires", "modreplaces", "modexcludes", "modretracts"} {
+		_, err := c.Exec(ctx, fmt.Sprintf(`DELETE FROM %s WHERE path = $1 AND version = $2;`, table), path, version)
+		if err != nil {
+			return fmt.Errorf("failed to clear %s: %w", table, err)
+		}
+	}
+	if f == nil {
+		return nil
+	}
+
+	var goVersion, toolchain string
+	if f.Go != nil {
+		goVersion = f.Go.Version
+	}
+	if f.Toolchain != nil {
+		toolchain = f.Toolchain.Name
+	}
+	_, err := c.Exec(ctx, `UPDATE modversions SET go_version = $3, toolchain = $4 WHERE path = $1 AND version = $2;`, path, version, goVersion, toolchain)
+	if err != nil {
+		return fmt.Errorf("failed to store go directive: %w", err)
+	}
+	for _, r := range f.Require {
+		_, err := c.Exec(ctx, `INSERT INTO modrequires (path, version, req_path, req_version, indirect) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (path, version, req_path) DO UPDATE SET req_version = $4, indirect = $5;`, path, version, r.Mod.Path, r.Mod.Version, r.Indirect)
+		if err != nil {
+			return fmt.Errorf("failed to store requirement %s: %w", r.Mod.Path, err)
+		}
+	}
+	for _, r := range f.Replace {
+		_, err := c.Exec(ctx, `INSERT INTO modreplaces (path, version, old_path, old_version, new_path, new_version) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (path, version, old_path, old_version) DO UPDATE SET new_path = $5, new_version = $6;`, path, version, r.Old.Path, r.Old.Version, r.New.Path, r.New.Version)
+		if err != nil {
+			return fmt.Errorf("failed to store replacement %s: %w", r.Old.Path, err)
+		}
+	}
+	for _, x := range f.Exclude {
+		_, err := c.Exec(ctx, `INSERT INTO modexcludes (path, version, ex_path, ex_version) VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING;`, path, version, x.Mod.Path, x.Mod.Version)
+		if err != nil {
+			return fmt.Errorf("failed to store exclusion %s: %w", x.Mod.Path, err)
+		}
+	}
+	for _, r := range f.Retract {
+		_, err := c.Exec(ctx, `INSERT INTO modretracts (path, version, low, high, rationale) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (path, version, low, high) DO UPDATE SET rationale = $5;`, path, version, r.Low, r.High, r.Rationale)
+		if err != nil {
+			return fmt.Errorf("failed to store retraction %s: %w", r.Low, err)
+		}
+	}
+	return nil
+}
+
+func (c *conn) PutDocs(ctx context.Context, path, version string, pkgs []*extract.Package) error {
+	_, err := c.Exec(ctx, `DELETE FROM symbols WHERE version = $2 AND pkg_path IN (SELECT path FROM pkgs WHERE mod_path = $1 AND version = $2);`, path, version)
+	if err != nil {
+		return fmt.Errorf("failed to clear symbols: %w", err)
+	}
+	_, err = c.Exec(ctx, `DELETE FROM pkgs WHERE mod_path = $1 AND version = $2;`, path, version)
+	if err != nil {
+		return fmt.Errorf("failed to clear packages: %w", err)
+	}
+	for _, p := range pkgs {
+		_, err := c.Exec(ctx, `INSERT INTO pkgs (path, version, mod_path, name, synopsis, doc) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (path, version) DO UPDATE SET mod_path = $3, name = $4, synopsis = $5, doc = $6;`, p.Path, version, path, p.Name, p.Synopsis, p.Doc)
+		if err != nil {
+			return fmt.Errorf("failed to store package %s: %w", p.Path, err)
+		}
+		for _, s := range p.Symbols {
+			_, err := c.Exec(ctx, `INSERT INTO symbols (pkg_path, version, name, kind, parent, signature, synopsis, doc) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (pkg_path, version, name) DO NOTHING;`, p.Path, version, s.Name, s.Kind, s.Parent, s.Signature, s.Synopsis, s.Doc)
+			if err != nil {
+				return fmt.Errorf("failed to store symbol %s.%s: %w", p.Path, s.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *conn) PutLicenses(ctx context.Context, path, version string, matches []extract.LicenseMatch) error {
+	_, err := c.Exec(ctx, `DELETE FROM modlicenses WHERE path = $1 AND version = $2;`, path, version)
+	if err != nil {
+		return fmt.Errorf("failed to clear licenses: %w", err)
+	}
+	for _, m := range matches {
+		_, err := c.Exec(ctx, `INSERT INTO modlicenses (path, version, license, file, confidence) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (path, version, license, file) DO UPDATE SET confidence = $5;`, path, version, m.License, m.File, m.Confidence)
+		if err != nil {
+			return fmt.Errorf("failed to store license %s: %w", m.License, err)
+		}
+	}
+	return nil
+}
+
+func (c *conn) SetPolicy(ctx context.Context, path, version, status string, redistributable bool) error {
+	_, err := c.Exec(ctx, `UPDATE modversions SET policy_status = $3, redistributable = $4 WHERE path = $1 AND version = $2;`, path, version, status, redistributable)
+	return err
+}
+
+func (c *conn) PutImports(ctx context.Context, path string, imports []extract.Import) error {
+	_, err := c.Exec(ctx, `DELETE FROM imports WHERE mod_path = $1;`, path)
+	if err != nil {
+		return fmt.Errorf("failed to clear imports: %w", err)
+	}
+	for _, i := range imports {
+		_, err := c.Exec(ctx, `INSERT INTO imports (mod_path, pkg_path, imported_path, imported_mod) VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING;`, path, i.PkgPath, i.ImportedPath, i.ImportedMod)
+		if err != nil {
+			return fmt.Errorf("failed to store import %s: %w", i.ImportedPath, err)
+		}
+	}
+	return nil
+}
+
+func (c *conn) RemoveModule(ctx context.Context, path string) (int64, error) {
+	var id int64
+	err := c.QueryRow(ctx, `DELETE FROM mods WHERE path = $1 RETURNING id`, path).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	_, err = c.Exec(ctx, `DELETE FROM modsmeta WHERE id = $1;`, id)
+	if err != nil {
+		return 0, err
+	}
+	_, err = c.Exec(ctx, `DELETE FROM imports WHERE mod_path = $1;`, path)
+	if err != nil {
+		return 0, err
+	}
+	_, err = c.Exec(ctx, `DELETE FROM symbols WHERE pkg_path IN (SELECT path FROM pkgs WHERE mod_path = $1);`, path)
+	if err != nil {
+		return 0, err
+	}
+	_, err = c.Exec(ctx, `DELETE FROM pkgs WHERE mod_path = $1;`, path)
+	if err != nil {
+		return 0, err
+	}
+	for _, table := range []string{"modversions", "modrequires", "modreplaces", "modexcludes", "modretracts", "modlicenses", "jobs"} {
+		_, err := c.Exec(ctx, fmt.Sprintf(`DELETE FROM %s WHERE path = $1;`, table), path)
+		if err != nil {
+			return 0, fmt.Errorf("failed to clear %s: %w", table, err)
+		}
+	}
+	return id, nil
+}
+
+func (c *conn) Cursor(ctx context.Context, key string) (string, error) {
+	var value sql.NullString
+	err := c.QueryRow(ctx, `SELECT value FROM utils WHERE key = $1`, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return value.String, err
+}
+
+func (c *conn) SetCursor(ctx context.Context, key, value string) error {
+	_, err := c.Exec(ctx, `INSERT INTO utils (key, value) VALUES ($1, $2) ON CONFLICT (key) DO UPDATE SET value = $2;`, key, value)
+	return err
+}
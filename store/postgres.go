@@ -0,0 +1,253 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+
+	"github.com/cockroachdb/cockroach-go/v2/crdb"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// postgres stores modules in CockroachDB. Apart from the schema, which uses
+// CockroachDB types, it also works with Postgres.
+type postgres struct {
+	*conn
+	db   *sql.DB
+	pool *pgxpool.Pool
+}
+
+// openPostgres connects through a pgxpool, so concurrent requests each get
+// their own connection. The pool is configured by the pool_* parameters of
+// url, such as pool_max_conns; maxConns overrides pool_max_conns if positive.
+func openPostgres(url string, maxConns int) (*postgres, error) {
+	cfg, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		return nil, err
+	}
+	if maxConns > 0 {
+		cfg.MaxConns = int32(maxConns)
+	}
+	pool, err := pgxpool.NewWithConfig(context.Background(), cfg)
+	if err != nil {
+		return nil, err
+	}
+	db := stdlib.OpenDBFromPool(pool)
+	err = db.PingContext(context.Background())
+	if err != nil {
+		db.Close()
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return &postgres{conn: newPostgresConn(db), db: db, pool: pool}, nil
+}
+
+func newPostgresConn(db sqlDB) *conn {
+	return &conn{
+		db:       db,
+		rebind:   func(query string) string { return query },
+		licenses: func(ids []string) any { return ids }, // STRING[] column
+	}
+}
+
+func (p *postgres) InTx(ctx context.Context, fn func(Tx) error) error {
+	return crdb.ExecuteTx(ctx, p.db, nil, func(tx *sql.Tx) error {
+		return fn(newPostgresConn(tx))
+	})
+}
+
+func (p *postgres) Migrations() []Migration {
+	return postgresMigrations
+}
+
+// TransactionalDDL is false, since CockroachDB does not allow mixing most
+// schema changes with other statements in a transaction.
+func (p *postgres) TransactionalDDL() bool {
+	return false
+}
+
+func (p *postgres) Close() error {
+	err := p.db.Close()
+	p.pool.Close()
+	return err
+}
+
+// postgresMigrations build the CockroachDB schema. The first one only
+// creates what is missing and adds the columns introduced before migrations
+// existed, so it also adopts databases created by earlier versions of pantry.
+var postgresMigrations = []Migration{{
+	Version: 1,
+	Name:    "initial schema",
+	Up: []string{
+		`CREATE TABLE IF NOT EXISTS mods (
+		id INT64 PRIMARY KEY DEFAULT unique_rowid(),
+		path TEXT NOT NULL UNIQUE,
+		version TEXT NOT NULL,
+		readme TEXT,
+		docs TEXT,
+		time TIMESTAMP);`,
+		`CREATE TABLE IF NOT EXISTS modsmeta (
+		id INT64 PRIMARY KEY,
+		license STRING,
+		licenses STRING[]);`,
+		`ALTER TABLE modsmeta ADD COLUMN IF NOT EXISTS policy_status STRING, ADD COLUMN IF NOT EXISTS redistributable BOOL;`,
+		`CREATE TABLE IF NOT EXISTS modversions (
+		path TEXT NOT NULL,
+		version TEXT NOT NULL,
+		readme TEXT,
+		docs TEXT,
+		time TIMESTAMP,
+		created_at TIMESTAMP NOT NULL DEFAULT now(),
+		updated_at TIMESTAMP NOT NULL DEFAULT now(),
+		PRIMARY KEY (path, version));`,
+		// Backfill versions stored before modversions existed
+		`INSERT INTO modversions (path, version, readme, docs, time)
+		SELECT path, version, readme, docs, time FROM mods ON CONFLICT (path, version) DO NOTHING;`,
+		`ALTER TABLE modversions ADD COLUMN IF NOT EXISTS go_version TEXT, ADD COLUMN IF NOT EXISTS toolchain TEXT;`,
+		`ALTER TABLE modversions ADD COLUMN IF NOT EXISTS policy_status TEXT, ADD COLUMN IF NOT EXISTS redistributable BOOL;`,
+		`CREATE TABLE IF NOT EXISTS modlicenses (
+		path TEXT NOT NULL,
+		version TEXT NOT NULL,
+		license TEXT NOT NULL,
+		file TEXT NOT NULL,
+		confidence FLOAT4 NOT NULL,
+		PRIMARY KEY (path, version, license, file));`,
+		`CREATE TABLE IF NOT EXISTS modrequires (
+		path TEXT NOT NULL,
+		version TEXT NOT NULL,
+		req_path TEXT NOT NULL,
+		req_version TEXT NOT NULL,
+		indirect BOOL NOT NULL DEFAULT false,
+		PRIMARY KEY (path, version, req_path),
+		INDEX (req_path));`,
+		`CREATE TABLE IF NOT EXISTS modreplaces (
+		path TEXT NOT NULL,
+		version TEXT NOT NULL,
+		old_path TEXT NOT NULL,
+		old_version TEXT NOT NULL DEFAULT '',
+		new_path TEXT NOT NULL,
+		new_version TEXT NOT NULL DEFAULT '',
+		PRIMARY KEY (path, version, old_path, old_version));`,
+		`CREATE TABLE IF NOT EXISTS modexcludes (
+		path TEXT NOT NULL,
+		version TEXT NOT NULL,
+		ex_path TEXT NOT NULL,
+		ex_version TEXT NOT NULL,
+		PRIMARY KEY (path, version, ex_path, ex_version));`,
+		`CREATE TABLE IF NOT EXISTS modretracts (
+		path TEXT NOT NULL,
+		version TEXT NOT NULL,
+		low TEXT NOT NULL,
+		high TEXT NOT NULL,
+		rationale TEXT,
+		PRIMARY KEY (path, version, low, high));`,
+		`CREATE TABLE IF NOT EXISTS imports (
+		mod_path TEXT NOT NULL,
+		pkg_path TEXT NOT NULL,
+		imported_path TEXT NOT NULL,
+		imported_mod TEXT NOT NULL,
+		PRIMARY KEY (mod_path, pkg_path, imported_path),
+		INDEX (imported_mod, mod_path));`,
+		`CREATE TABLE IF NOT EXISTS pkgs (
+		path TEXT NOT NULL,
+		version TEXT NOT NULL,
+		mod_path TEXT NOT NULL,
+		name TEXT NOT NULL,
+		synopsis TEXT,
+		doc TEXT,
+		PRIMARY KEY (path, version),
+		INDEX (mod_path, version));`,
+		`CREATE TABLE IF NOT EXISTS symbols (
+		pkg_path TEXT NOT NULL,
+		version TEXT NOT NULL,
+		name TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		parent TEXT NOT NULL DEFAULT '',
+		signature TEXT NOT NULL,
+		doc TEXT,
+		PRIMARY KEY (pkg_path, version, name));`,
+		`ALTER TABLE symbols ADD COLUMN IF NOT EXISTS id INT64 NOT NULL DEFAULT unique_rowid() UNIQUE, ADD COLUMN IF NOT EXISTS synopsis TEXT;`,
+		`CREATE TABLE IF NOT EXISTS utils (
+		key STRING NOT NULL PRIMARY KEY,
+		value STRING);`,
+	},
+	Down: []string{
+		`DROP TABLE IF EXISTS utils;`,
+		`DROP TABLE IF EXISTS symbols;`,
+		`DROP TABLE IF EXISTS pkgs;`,
+		`DROP TABLE IF EXISTS imports;`,
+		`DROP TABLE IF EXISTS modretracts;`,
+		`DROP TABLE IF EXISTS modexcludes;`,
+		`DROP TABLE IF EXISTS modreplaces;`,
+		`DROP TABLE IF EXISTS modrequires;`,
+		`DROP TABLE IF EXISTS modlicenses;`,
+		`DROP TABLE IF EXISTS modversions;`,
+		`DROP TABLE IF EXISTS modsmeta;`,
+		`DROP TABLE IF EXISTS mods;`,
+	},
+	Adopt: repairModsKey,
+}, {
+	Version: 2,
+	Name:    "scan jobs",
+	Up: []string{
+		`CREATE TABLE IF NOT EXISTS jobs (
+		path TEXT PRIMARY KEY,
+		state STRING NOT NULL,
+		attempts INT NOT NULL DEFAULT 0,
+		last_error STRING,
+		next_attempt TIMESTAMP NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT now(),
+		updated_at TIMESTAMP NOT NULL DEFAULT now(),
+		INDEX jobs_due (state, next_attempt));`,
+	},
+	Down: []string{
+		`DROP TABLE IF EXISTS jobs;`,
+	},
+}, {
+	Version: 3,
+	Name:    "versioned jobs",
+	Up: []string{
+		`ALTER TABLE jobs ADD COLUMN IF NOT EXISTS version STRING NOT NULL DEFAULT '';`,
+		`ALTER TABLE jobs DROP CONSTRAINT jobs_pkey, ADD CONSTRAINT jobs_pkey PRIMARY KEY (path, version);`,
+	},
+	Down: []string{
+		`DELETE FROM jobs WHERE version <> '';`,
+		`ALTER TABLE jobs DROP CONSTRAINT jobs_pkey, ADD CONSTRAINT jobs_pkey PRIMARY KEY (path);`,
+		`ALTER TABLE jobs DROP COLUMN version;`,
+	},
+}}
+
+// repairModsKey returns the statements keying the mods table on id, if it
+// exists with another primary key. Earlier versions of the server created
+// mods without one, so CockroachDB keyed it on a hidden rowid column.
+func repairModsKey(ctx context.Context, q Querier) ([]string, error) {
+	key, exists, err := primaryKey(ctx, q, "mods")
+	if err != nil || !exists || slices.Equal(key, []string{"id"}) {
+		return nil, err
+	}
+	return []string{
+		`ALTER TABLE mods ALTER COLUMN id SET NOT NULL;`,
+		`ALTER TABLE mods ALTER PRIMARY KEY USING COLUMNS (id);`,
+	}, nil
+}
+
+// primaryKey returns the columns of the primary key of table, in the current
+// schema, and whether the table exists.
+func primaryKey(ctx context.Context, q Querier, table string) ([]string, bool, error) {
+	var n int
+	err := q.QueryRow(ctx, `SELECT count(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = $1`, table).Scan(&n)
+	if err != nil || n == 0 {
+		return nil, false, err
+	}
+	rows, err := q.Query(ctx, `SELECT k.column_name FROM information_schema.table_constraints c
+	JOIN information_schema.key_column_usage k ON k.table_schema = c.table_schema AND k.table_name = c.table_name AND k.constraint_name = c.constraint_name
+	WHERE c.table_schema = current_schema() AND c.table_name = $1 AND c.constraint_type = 'PRIMARY KEY'
+	ORDER BY k.ordinal_position`, table)
+	if err != nil {
+		return nil, true, err
+	}
+	key, err := CollectRows(rows, RowTo[string])
+	return key, true, err
+}
@@ -0,0 +1,264 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	_ "modernc.org/sqlite" // Registers the sqlite driver; pure Go, so builds need no cgo
+)
+
+// sqlite stores modules in a single SQLite file, so the scanner and the
+// server can run without a database server.
+type sqlite struct {
+	*conn
+	db *sql.DB
+}
+
+// openSQLite opens the database in path. maxConns limits the number of open
+// connections if positive.
+func openSQLite(path string, maxConns int) (*sqlite, error) {
+	if path == "" {
+		return nil, errors.New("missing SQLite database file")
+	}
+	err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+	// WAL lets the server read while the scanner writes. Transactions take the
+	// write lock up front so that two writers wait for each other instead of
+	// failing when one upgrades a read to a write. Times are written in a
+	// format SQLite's date functions understand.
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(10000)&_txlock=immediate&_time_format=sqlite")
+	if err != nil {
+		return nil, err
+	}
+	if maxConns > 0 {
+		db.SetMaxOpenConns(maxConns)
+	}
+	err = db.PingContext(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	return &sqlite{conn: newSQLiteConn(db), db: db}, nil
+}
+
+func newSQLiteConn(db sqlDB) *conn {
+	return &conn{
+		db:     db,
+		rebind: sqliteRebind,
+		// SQLite has no arrays; keep the space-separated form the search indexes use
+		licenses: func(ids []string) any { return strings.Join(ids, " ") },
+	}
+}
+
+var placeholder = regexp.MustCompile(`\$(\d+)`)
+
+// sqliteRebind rewrites $1-style placeholders to ?1. SQLite reads $1 as a named
+// parameter and numbers parameters in order of appearance, which breaks
+// queries that use $2 before $1.
+func sqliteRebind(query string) string {
+	return placeholder.ReplaceAllString(query, "?$1")
+}
+
+func (s *sqlite) InTx(ctx context.Context, fn func(Tx) error) (err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, tx.Rollback())
+		}
+	}()
+	err = fn(newSQLiteConn(tx))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqlite) Migrations() []Migration {
+	return sqliteMigrations
+}
+
+func (s *sqlite) TransactionalDDL() bool {
+	return true
+}
+
+func (s *sqlite) Close() error {
+	return s.db.Close()
+}
+
+// sqliteMigrations build the same schema as postgresMigrations, using
+// SQLite's types. Integer primary keys take the place of unique_rowid().
+var sqliteMigrations = []Migration{{
+	Version: 1,
+	Name:    "initial schema",
+	Up: []string{
+		`CREATE TABLE IF NOT EXISTS mods (
+		id INTEGER PRIMARY KEY,
+		path TEXT NOT NULL UNIQUE,
+		version TEXT NOT NULL,
+		readme TEXT,
+		docs TEXT,
+		time TIMESTAMP);`,
+		`CREATE TABLE IF NOT EXISTS modsmeta (
+		id INTEGER PRIMARY KEY,
+		license TEXT,
+		licenses TEXT,
+		policy_status TEXT,
+		redistributable BOOLEAN);`,
+		`CREATE TABLE IF NOT EXISTS modversions (
+		path TEXT NOT NULL,
+		version TEXT NOT NULL,
+		readme TEXT,
+		docs TEXT,
+		time TIMESTAMP,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		go_version TEXT,
+		toolchain TEXT,
+		policy_status TEXT,
+		redistributable BOOLEAN,
+		PRIMARY KEY (path, version));`,
+		`CREATE TABLE IF NOT EXISTS modlicenses (
+		path TEXT NOT NULL,
+		version TEXT NOT NULL,
+		license TEXT NOT NULL,
+		file TEXT NOT NULL,
+		confidence REAL NOT NULL,
+		PRIMARY KEY (path, version, license, file));`,
+		`CREATE TABLE IF NOT EXISTS modrequires (
+		path TEXT NOT NULL,
+		version TEXT NOT NULL,
+		req_path TEXT NOT NULL,
+		req_version TEXT NOT NULL,
+		indirect BOOLEAN NOT NULL DEFAULT false,
+		PRIMARY KEY (path, version, req_path));`,
+		`CREATE INDEX IF NOT EXISTS modrequires_req_path ON modrequires (req_path);`,
+		`CREATE TABLE IF NOT EXISTS modreplaces (
+		path TEXT NOT NULL,
+		version TEXT NOT NULL,
+		old_path TEXT NOT NULL,
+		old_version TEXT NOT NULL DEFAULT '',
+		new_path TEXT NOT NULL,
+		new_version TEXT NOT NULL DEFAULT '',
+		PRIMARY KEY (path, version, old_path, old_version));`,
+		`CREATE TABLE IF NOT EXISTS modexcludes (
+		path TEXT NOT NULL,
+		version TEXT NOT NULL,
+		ex_path TEXT NOT NULL,
+		ex_version TEXT NOT NULL,
+		PRIMARY KEY (path, version, ex_path, ex_version));`,
+		`CREATE TABLE IF NOT EXISTS modretracts (
+		path TEXT NOT NULL,
+		version TEXT NOT NULL,
+		low TEXT NOT NULL,
+		high TEXT NOT NULL,
+		rationale TEXT,
+		PRIMARY KEY (path, version, low, high));`,
+		`CREATE TABLE IF NOT EXISTS imports (
+		mod_path TEXT NOT NULL,
+		pkg_path TEXT NOT NULL,
+		imported_path TEXT NOT NULL,
+		imported_mod TEXT NOT NULL,
+		PRIMARY KEY (mod_path, pkg_path, imported_path));`,
+		`CREATE INDEX IF NOT EXISTS imports_imported_mod ON imports (imported_mod, mod_path);`,
+		`CREATE TABLE IF NOT EXISTS pkgs (
+		path TEXT NOT NULL,
+		version TEXT NOT NULL,
+		mod_path TEXT NOT NULL,
+		name TEXT NOT NULL,
+		synopsis TEXT,
+		doc TEXT,
+		PRIMARY KEY (path, version));`,
+		`CREATE INDEX IF NOT EXISTS pkgs_mod_path ON pkgs (mod_path, version);`,
+		`CREATE TABLE IF NOT EXISTS symbols (
+		id INTEGER PRIMARY KEY,
+		pkg_path TEXT NOT NULL,
+		version TEXT NOT NULL,
+		name TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		parent TEXT NOT NULL DEFAULT '',
+		signature TEXT NOT NULL,
+		synopsis TEXT,
+		doc TEXT,
+		UNIQUE (pkg_path, version, name));`,
+		`CREATE TABLE IF NOT EXISTS utils (
+		key TEXT NOT NULL PRIMARY KEY,
+		value TEXT);`,
+	},
+	Down: []string{
+		`DROP TABLE IF EXISTS utils;`,
+		`DROP TABLE IF EXISTS symbols;`,
+		`DROP TABLE IF EXISTS pkgs;`,
+		`DROP TABLE IF EXISTS imports;`,
+		`DROP TABLE IF EXISTS modretracts;`,
+		`DROP TABLE IF EXISTS modexcludes;`,
+		`DROP TABLE IF EXISTS modreplaces;`,
+		`DROP TABLE IF EXISTS modrequires;`,
+		`DROP TABLE IF EXISTS modlicenses;`,
+		`DROP TABLE IF EXISTS modversions;`,
+		`DROP TABLE IF EXISTS modsmeta;`,
+		`DROP TABLE IF EXISTS mods;`,
+	},
+}, {
+	Version: 2,
+	Name:    "scan jobs",
+	Up: []string{
+		`CREATE TABLE IF NOT EXISTS jobs (
+		path TEXT PRIMARY KEY,
+		state TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT,
+		next_attempt TIMESTAMP NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP);`,
+		`CREATE INDEX IF NOT EXISTS jobs_due ON jobs (state, next_attempt);`,
+	},
+	Down: []string{
+		`DROP TABLE IF EXISTS jobs;`,
+	},
+}, {
+	Version: 3,
+	Name:    "versioned jobs",
+	// SQLite cannot change a primary key, so the table is copied
+	Up: []string{
+		`CREATE TABLE IF NOT EXISTS jobs_v3 (
+		path TEXT NOT NULL,
+		version TEXT NOT NULL DEFAULT '',
+		state TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT,
+		next_attempt TIMESTAMP NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (path, version));`,
+		`INSERT INTO jobs_v3 (path, state, attempts, last_error, next_attempt, created_at, updated_at)
+		SELECT path, state, attempts, last_error, next_attempt, created_at, updated_at FROM jobs;`,
+		`DROP TABLE jobs;`,
+		`ALTER TABLE jobs_v3 RENAME TO jobs;`,
+		`CREATE INDEX IF NOT EXISTS jobs_due ON jobs (state, next_attempt);`,
+	},
+	Down: []string{
+		`CREATE TABLE IF NOT EXISTS jobs_v2 (
+		path TEXT PRIMARY KEY,
+		state TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT,
+		next_attempt TIMESTAMP NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP);`,
+		`INSERT INTO jobs_v2 (path, state, attempts, last_error, next_attempt, created_at, updated_at)
+		SELECT path, state, attempts, last_error, next_attempt, created_at, updated_at FROM jobs WHERE version = '';`,
+		`DROP TABLE jobs;`,
+		`ALTER TABLE jobs_v2 RENAME TO jobs;`,
+		`CREATE INDEX IF NOT EXISTS jobs_due ON jobs (state, next_attempt);`,
+	},
+}}
@@ -0,0 +1,321 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fflewddur/pantry/extract"
+	"golang.org/x/mod/modfile"
+)
+
+// openEmptyStore returns a SQLite database in a temporary directory of t,
+// without any migration applied. Tests outside this package use
+// storetest.New, which this package cannot import.
+func openEmptyStore(t *testing.T) Store {
+	t.Helper()
+	s, err := Open("sqlite:" + filepath.Join(t.TempDir(), "pantry.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func openTestStore(t *testing.T) Store {
+	t.Helper()
+	s := openEmptyStore(t)
+	_, err := Up(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSQLiteModules(t *testing.T) {
+	ctx := context.Background()
+	s := openTestStore(t)
+	_, _, err := s.LatestVersion(ctx, "example.com/hello")
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("LatestVersion of an unknown module: err = %v, want sql.ErrNoRows", err)
+	}
+
+	modTime := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	for _, version := range []string{"v1.0.0", "v1.1.0"} {
+		m := &Module{Path: "example.com/hello", Version: version, Readme: "Hello", Time: modTime}
+		err = s.InTx(ctx, func(tx Tx) error {
+			err := tx.PutVersion(ctx, m)
+			if err != nil {
+				return err
+			}
+			err = tx.PutModule(ctx, m)
+			if err != nil {
+				return err
+			}
+			return tx.PutMeta(ctx, &Meta{ID: m.ID, License: "MIT", Licenses: []string{"MIT", "0BSD"}, Redistributable: true})
+		})
+		if err != nil {
+			t.Fatalf("storing %s: %v", version, err)
+		}
+	}
+	id, version, err := s.LatestVersion(ctx, "example.com/hello")
+	if err != nil || version != "v1.1.0" {
+		t.Fatalf("LatestVersion = %d, %q, %v; want v1.1.0", id, version, err)
+	}
+	for v, want := range map[string]bool{"v1.0.0": true, "v1.2.0": false} {
+		if ok, err := s.HasVersion(ctx, "example.com/hello", v); ok != want || err != nil {
+			t.Errorf("HasVersion(%s) = %v, %v; want %v", v, ok, err, want)
+		}
+	}
+
+	// $2 comes before $1 here, which SQLite numbers in order of appearance
+	var path string
+	var redistributable bool
+	var licenses string
+	var stored time.Time
+	err = s.QueryRow(ctx, `SELECT m.path, m.time, mm.redistributable, mm.licenses FROM mods m JOIN modsmeta mm ON mm.id = m.id
+	WHERE m.version = $2 AND m.path = $1`, "example.com/hello", "v1.1.0").Scan(&path, &stored, &redistributable, &licenses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.Equal(modTime) || !redistributable || licenses != "MIT 0BSD" {
+		t.Errorf("got time %v, redistributable %v, licenses %q", stored, redistributable, licenses)
+	}
+	rows, err := s.Query(ctx, `SELECT version, readme FROM modversions WHERE path = $1 ORDER BY version`, "example.com/hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions, err := CollectRows(rows, RowToAddrOfStructByPos[struct {
+		Version string
+		Readme  string
+	}])
+	if err != nil || len(versions) != 2 || versions[1].Version != "v1.1.0" {
+		t.Errorf("modversions = %v, %v", versions, err)
+	}
+}
+
+// count returns the number of rows of table about the module at path.
+func count(t *testing.T, s Store, table, column, path string) int {
+	t.Helper()
+	var n int
+	err := s.QueryRow(context.Background(), fmt.Sprintf(`SELECT count(*) FROM %s WHERE %s = $1`, table, column), path).Scan(&n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestSQLiteModuleData(t *testing.T) {
+	ctx := context.Background()
+	s := openTestStore(t)
+	const path = "example.com/hello"
+	goMod, err := modfile.Parse("go.mod", []byte(`module example.com/hello
+
+go 1.24
+
+require golang.org/x/mod v0.25.0
+
+replace example.com/old => example.com/new v1.0.0
+
+exclude example.com/bad v1.0.0
+
+retract v0.9.0 // Published by mistake
+`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &Module{Path: path, Version: "v1.0.0", Time: time.Now()}
+	pkgs := []*extract.Package{{
+		Path:    path,
+		Name:    "hello",
+		Symbols: []extract.Symbol{{Name: "Hello", Kind: "func"}, {Name: "World", Kind: "const"}},
+	}}
+	err = s.InTx(ctx, func(tx Tx) error {
+		err := tx.PutVersion(ctx, m)
+		if err != nil {
+			return err
+		}
+		err = tx.PutGoMod(ctx, path, m.Version, goMod)
+		if err != nil {
+			return err
+		}
+		err = tx.PutDocs(ctx, path, m.Version, pkgs)
+		if err != nil {
+			return err
+		}
+		err = tx.PutLicenses(ctx, path, m.Version, []extract.LicenseMatch{{License: "MIT", File: "LICENSE", Confidence: 0.98}})
+		if err != nil {
+			return err
+		}
+		err = tx.SetPolicy(ctx, path, m.Version, "allowed", true)
+		if err != nil {
+			return err
+		}
+		err = tx.PutModule(ctx, m)
+		if err != nil {
+			return err
+		}
+		return tx.PutImports(ctx, path, []extract.Import{{PkgPath: path, ImportedPath: "golang.org/x/mod/modfile", ImportedMod: "golang.org/x/mod"}})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var goVersion, status string
+	var redistributable bool
+	err = s.QueryRow(ctx, `SELECT go_version, policy_status, redistributable FROM modversions WHERE path = $1`, path).Scan(&goVersion, &status, &redistributable)
+	if err != nil || goVersion != "1.24" || status != "allowed" || !redistributable {
+		t.Errorf("modversions has go %q, policy %q, redistributable %v, %v", goVersion, status, redistributable, err)
+	}
+	for _, table := range []string{"modrequires", "modreplaces", "modexcludes", "modretracts", "modlicenses"} {
+		if n := count(t, s, table, "path", path); n != 1 {
+			t.Errorf("%d rows in %s, want 1", n, table)
+		}
+	}
+	if n := count(t, s, "symbols", "pkg_path", path); n != 2 {
+		t.Errorf("%d symbols, want 2", n)
+	}
+	if n := count(t, s, "imports", "mod_path", path); n != 1 {
+		t.Errorf("%d imports, want 1", n)
+	}
+
+	// Storing the version again replaces its data
+	pkgs[0].Symbols = pkgs[0].Symbols[:1]
+	err = s.InTx(ctx, func(tx Tx) error {
+		err := tx.PutGoMod(ctx, path, m.Version, nil)
+		if err != nil {
+			return err
+		}
+		return tx.PutDocs(ctx, path, m.Version, pkgs)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := count(t, s, "modrequires", "path", path); n != 0 {
+		t.Errorf("%d requirements after storing a module without go.mod, want 0", n)
+	}
+	if n := count(t, s, "symbols", "pkg_path", path); n != 1 {
+		t.Errorf("%d symbols after storing the version again, want 1", n)
+	}
+
+	id, err := s.RemoveModule(ctx, path)
+	if err != nil || id != m.ID {
+		t.Fatalf("RemoveModule = %d, %v; want %d", id, err, m.ID)
+	}
+	for _, table := range []string{"mods", "modversions", "modlicenses", "modretracts"} {
+		if n := count(t, s, table, "path", path); n != 0 {
+			t.Errorf("%d rows left in %s after RemoveModule", n, table)
+		}
+	}
+	for _, table := range []string{"pkgs", "imports"} {
+		if n := count(t, s, table, "mod_path", path); n != 0 {
+			t.Errorf("%d rows left in %s after RemoveModule", n, table)
+		}
+	}
+	if n := count(t, s, "symbols", "pkg_path", path); n != 0 {
+		t.Errorf("%d symbols left after RemoveModule", n)
+	}
+	_, err = s.RemoveModule(ctx, path)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("RemoveModule of a removed module: err = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestSQLiteCursor(t *testing.T) {
+	ctx := context.Background()
+	s := openTestStore(t)
+	since, err := s.Cursor(ctx, "since")
+	if err != nil || since != "" {
+		t.Fatalf("Cursor before it is set = %q, %v", since, err)
+	}
+	for _, value := range []string{"2025-07-01T12:00:00Z", "2025-07-02T12:00:00Z"} {
+		err = s.SetCursor(ctx, "since", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	since, err = s.Cursor(ctx, "since")
+	if err != nil || since != "2025-07-02T12:00:00Z" {
+		t.Errorf("Cursor = %q, %v", since, err)
+	}
+}
+
+func TestOpenUnknownScheme(t *testing.T) {
+	_, err := Open("mysql://localhost/pantry")
+	if err == nil {
+		t.Error("Open accepted a mysql URL")
+	}
+}
+
+func TestSQLiteJobs(t *testing.T) {
+	ctx := context.Background()
+	s := openTestStore(t)
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	for _, path := range []string{"example.com/a", "example.com/b", "example.com/a"} {
+		err := s.AddJob(ctx, path, "", now)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A job for an exact version is separate from the one for the latest version
+	err := s.AddJob(ctx, "example.com/a", "v1.0.0", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	due, err := s.DueJobs(ctx, now, 10)
+	if err != nil || len(due) != 3 || due[0].State != JobPending || due[1].Version != "v1.0.0" {
+		t.Fatalf("DueJobs = %v, %v; want three pending jobs", due, err)
+	}
+	err = s.CompleteJob(ctx, "example.com/a", "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.FailJob(ctx, "example.com/a", "", "proxy returned 500", now.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.CompleteJob(ctx, "example.com/b", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	due, err = s.DueJobs(ctx, now.Add(30*time.Second), 10)
+	if err != nil || len(due) != 0 {
+		t.Fatalf("DueJobs before the retry = %v, %v; want none", due, err)
+	}
+	due, err = s.DueJobs(ctx, now.Add(time.Minute), 10)
+	if err != nil || len(due) != 1 || due[0].Attempts != 1 || due[0].LastError != "proxy returned 500" {
+		t.Fatalf("DueJobs after the backoff = %v, %v; want the failed job", due, err)
+	}
+
+	// Queueing a failed job again keeps its backoff
+	err = s.AddJob(ctx, "example.com/a", "", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	due, err = s.DueJobs(ctx, now, 10)
+	if err != nil || len(due) != 0 {
+		t.Fatalf("DueJobs after re-adding a failed job = %v, %v; want none", due, err)
+	}
+
+	err = s.FailJob(ctx, "example.com/a", "", "not found", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dead, err := s.JobsInState(ctx, JobDead)
+	if err != nil || len(dead) != 1 || dead[0].Attempts != 2 || dead[0].LastError != "not found" {
+		t.Fatalf("dead jobs = %v, %v", dead, err)
+	}
+	err = s.AddJob(ctx, "example.com/a", "", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	due, err = s.DueJobs(ctx, now, 10)
+	if err != nil || len(due) != 1 || due[0].State != JobPending || due[0].Attempts != 0 {
+		t.Fatalf("DueJobs after reviving a dead job = %v, %v", due, err)
+	}
+}
@@ -0,0 +1,27 @@
+// Package storetest provides throwaway databases, so that code using the
+// store package can be tested without a CockroachDB server.
+package storetest
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/fflewddur/pantry/store"
+)
+
+// New returns an empty SQLite database in a temporary directory of t,
+// migrated to the latest schema. It is closed when t finishes.
+func New(t *testing.T) store.Store {
+	t.Helper()
+	s, err := store.Open("sqlite:" + filepath.Join(t.TempDir(), "pantry.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	_, err = store.Up(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}